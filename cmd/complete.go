@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"os"
 	"sort"
 	"strings"
 
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/parser"
 	"github.com/bab-sh/bab/internal/runner"
 	"github.com/spf13/cobra"
 )
@@ -13,14 +16,15 @@ func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	babfile, _ := cmd.Flags().GetString("babfile")
-	result, err := runner.LoadTasks(babfile)
+	result, err := loadForCompletion(cmd)
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
+	tasks := result.Tasks.ForPlatform(babfile.CurrentPlatform())
+
 	var completions []string
-	for taskName, task := range result.Tasks {
+	for taskName, task := range tasks {
 		if task == nil || !strings.HasPrefix(taskName, toComplete) {
 			continue
 		}
@@ -36,7 +40,10 @@ func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]
 		if !strings.HasPrefix(alias, toComplete) {
 			continue
 		}
-		task := result.Tasks[taskName]
+		task, ok := tasks[taskName]
+		if !ok {
+			continue
+		}
 		if task != nil && task.Desc != "" {
 			completions = append(completions, alias+"\t"+task.Desc+" (alias)")
 		} else {
@@ -47,3 +54,38 @@ func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]
 	sort.Strings(completions)
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
+
+func completeProfiles(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	result, err := loadForCompletion(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for name, profile := range result.Profiles {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		if profile.Desc != "" {
+			completions = append(completions, name+"\t"+profile.Desc)
+		} else {
+			completions = append(completions, name)
+		}
+	}
+
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func loadForCompletion(cmd *cobra.Command) (*parser.ParseResult, error) {
+	if dir, _ := cmd.Flags().GetString("directory"); dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			return nil, err
+		}
+	}
+	if global, _ := cmd.Flags().GetBool("global"); global {
+		return runner.LoadGlobalTasks()
+	}
+	babfilePath, _ := cmd.Flags().GetString("babfile")
+	return runner.LoadTasksWithGlobal(babfilePath)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -195,3 +196,37 @@ func TestCompleteTaskNames_CompletionsAreSorted(t *testing.T) {
 		}
 	}
 }
+
+func TestCompleteTaskNames_HidesOtherPlatforms(t *testing.T) {
+	tmpDir := t.TempDir()
+	babfilePath := filepath.Join(tmpDir, "Babfile.yml")
+	babfileContent := `tasks:
+  everywhere:
+    run:
+      - cmd: echo everywhere
+  elsewhere:
+    alias: el
+    platforms: ["!` + runtime.GOOS + `"]
+    run:
+      - cmd: echo elsewhere`
+
+	if err := os.WriteFile(babfilePath, []byte(babfileContent), 0600); err != nil {
+		t.Fatalf("failed to create test Babfile: %v", err)
+	}
+
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	cmd := &cobra.Command{}
+	completions, _ := completeTaskNames(cmd, []string{}, "e")
+
+	if len(completions) != 1 || completions[0] != "everywhere" {
+		t.Errorf("expected only everywhere, got %v", completions)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/bab-sh/bab/internal/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +19,6 @@ func (c *CLI) runCompletion(cmd *cobra.Command) error {
 	case "powershell":
 		return cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
 	default:
-		return fmt.Errorf("invalid shell %q: must be one of: bash, zsh, fish, powershell", c.completion)
+		return &errs.UsageError{Err: fmt.Errorf("invalid shell %q: must be one of: bash, zsh, fish, powershell", c.completion)}
 	}
 }
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/bab-sh/bab/internal/remember"
+	"github.com/charmbracelet/log"
+)
+
+func (c *CLI) runForget() error {
+	result, err := c.loadTasks()
+	if err != nil {
+		return err
+	}
+
+	memory, err := remember.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := memory.Forget(result.Path); err != nil {
+		return err
+	}
+
+	log.Info("Forgot remembered answers", "babfile", result.Path)
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/finder"
+	"github.com/bab-sh/bab/internal/runner"
+	"github.com/charmbracelet/log"
+)
+
+const includesCommand = "includes"
+
+func (c *CLI) runIncludes(args []string) error {
+	if len(args) != 1 || args[0] != "update" {
+		return &errs.UsageError{Err: fmt.Errorf("usage: bab %s update", includesCommand)}
+	}
+
+	path := c.babfile
+	if c.global {
+		global, err := finder.FindGlobalBabfile()
+		if err != nil {
+			return err
+		}
+		path = global
+	}
+
+	lock, err := runner.UpdateIncludes(path)
+	if err != nil {
+		return err
+	}
+
+	entries := lock.Entries()
+	if len(entries) == 0 {
+		log.Warn("No remote includes found")
+		return nil
+	}
+	for _, e := range entries {
+		log.Info("Pinned include", "git", e.Git, "ref", e.Ref, "commit", shortCommit(e.Commit))
+	}
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -1,18 +1,22 @@
 package cmd
 
 import (
-	"github.com/bab-sh/bab/internal/runner"
 	"github.com/bab-sh/bab/internal/tui"
 	"github.com/charmbracelet/log"
 )
 
 func (c *CLI) runInteractive() error {
-	result, err := runner.LoadTasks(c.babfile)
+	result, err := c.loadTasksWithGlobal()
 	if err != nil {
 		return err
 	}
 
-	selected, err := tui.PickTask(c.ctx, result.Tasks)
+	platform, err := c.targetPlatform()
+	if err != nil {
+		return err
+	}
+
+	selected, err := tui.PickTask(c.ctx, result.Tasks.ForPlatform(platform))
 	if err != nil {
 		return err
 	}
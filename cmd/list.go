@@ -7,7 +7,6 @@ import (
 
 	"charm.land/lipgloss/v2"
 	"charm.land/lipgloss/v2/tree"
-	"github.com/bab-sh/bab/internal/runner"
 	"github.com/bab-sh/bab/internal/theme"
 	"github.com/charmbracelet/log"
 )
@@ -15,22 +14,29 @@ import (
 type node struct {
 	desc     string
 	aliases  []string
+	local    bool
 	children map[string]*node
 }
 
 func (c *CLI) runList() error {
-	result, err := runner.LoadTasks(c.babfile)
+	result, err := c.loadTasksWithGlobal()
 	if err != nil {
 		return err
 	}
 
-	if len(result.Tasks) == 0 {
+	platform, err := c.targetPlatform()
+	if err != nil {
+		return err
+	}
+	tasks := result.Tasks.ForPlatform(platform)
+
+	if len(tasks) == 0 {
 		log.Warn("No tasks found")
 		return nil
 	}
 
 	root := &node{children: make(map[string]*node)}
-	for name, task := range result.Tasks {
+	for name, task := range tasks {
 		parts := strings.Split(name, ":")
 		current := root
 		for i, part := range parts {
@@ -41,6 +47,7 @@ func (c *CLI) runList() error {
 			if i == len(parts)-1 {
 				current.desc = task.Desc
 				current.aliases = task.GetAllAliases()
+				current.local = task.Local
 			}
 		}
 	}
@@ -49,6 +56,7 @@ func (c *CLI) runList() error {
 	itemStyle := lipgloss.NewStyle().Foreground(theme.Purple).Bold(true)
 	descStyle := lipgloss.NewStyle().Foreground(theme.Muted).Italic(true)
 	aliasStyle := lipgloss.NewStyle().Foreground(theme.Cyan).Italic(true)
+	localStyle := lipgloss.NewStyle().Foreground(theme.Pink).Italic(true)
 
 	var buildTree func(*node, string) *tree.Tree
 	buildTree = func(n *node, name string) *tree.Tree {
@@ -56,6 +64,9 @@ func (c *CLI) runList() error {
 		if len(n.aliases) > 0 {
 			label += " " + aliasStyle.Render("("+strings.Join(n.aliases, ", ")+")")
 		}
+		if n.local {
+			label += " " + localStyle.Render("(local)")
+		}
 		if n.desc != "" {
 			label += " " + descStyle.Render(n.desc)
 		}
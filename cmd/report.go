@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+func parseReportSpec(spec string) (string, error) {
+	format, path, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("invalid report %q: expected format=path (e.g. junit=report.xml)", spec)
+	}
+	switch format {
+	case "junit":
+		return path, nil
+	default:
+		return "", fmt.Errorf("invalid report format %q: must be one of: junit", format)
+	}
+}
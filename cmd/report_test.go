@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseReportSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{spec: "junit=report.xml", want: "report.xml"},
+		{spec: "junit=out/junit.xml", want: "out/junit.xml"},
+		{spec: "junit", wantErr: "expected format=path"},
+		{spec: "junit=", wantErr: "expected format=path"},
+		{spec: "tap=report.tap", wantErr: "invalid report format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseReportSpec(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseReportSpec(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReportSpec(%q) unexpected error: %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseReportSpec(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLI_runTaskWritesReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	babfilePath := filepath.Join(tmpDir, "Babfile.yml")
+	reportPath := filepath.Join(tmpDir, "reports", "junit.xml")
+
+	babfileYAML := `tasks:
+  fail:
+    run:
+      - cmd: echo failing
+        output: false
+      - cmd: exit 3`
+	if err := os.WriteFile(babfilePath, []byte(babfileYAML), 0600); err != nil {
+		t.Fatalf("failed to create Babfile: %v", err)
+	}
+
+	c := newCLI()
+	c.ctx = context.Background()
+	c.babfile = babfilePath
+	c.report = "junit=" + reportPath
+	c.reportCommands = true
+
+	if err := c.runTask("fail"); err == nil {
+		t.Fatal("expected task to fail")
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	xml := string(data)
+	for _, want := range []string{`<testcase name="fail"`, `<testcase name="exit 3"`, "<failure", "failing"} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("report missing %q:\n%s", want, xml)
+		}
+	}
+}
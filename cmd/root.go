@@ -2,14 +2,27 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/bab-sh/bab/internal/answers"
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/ci"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/finder"
+	"github.com/bab-sh/bab/internal/parser"
+	"github.com/bab-sh/bab/internal/remember"
+	"github.com/bab-sh/bab/internal/report"
 	"github.com/bab-sh/bab/internal/runner"
 	"github.com/bab-sh/bab/internal/update"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
+const profileEnv = "BAB_PROFILE"
+
 var (
 	versionString = "dev"
 	versionShort  = "dev"
@@ -24,13 +37,30 @@ func SetVersionInfo(version, commit, date string) {
 }
 
 type CLI struct {
-	ctx        context.Context
-	verbose    bool
-	dryRun     bool
-	listTasks  bool
-	validate   bool
-	completion string
-	babfile    string
+	ctx            context.Context
+	verbose        bool
+	dryRun         bool
+	listTasks      bool
+	validate       bool
+	completion     string
+	babfile        string
+	report         string
+	reportCommands bool
+	ci             string
+	exitCode       string
+	answers        []string
+	answersFile    string
+	forget         bool
+	platform       string
+	recursive      bool
+	maxDepth       int
+	parallel       bool
+	affected       bool
+	base           string
+	global         bool
+	directory      string
+	profile        string
+	yes            bool
 }
 
 func ExecuteContext(ctx context.Context) error {
@@ -48,6 +78,12 @@ func (c *CLI) execute(ctx context.Context) error {
 
 	err := c.buildCommand().Execute()
 
+	if err != nil {
+		if provider, perr := ci.Resolve(c.ci); perr == nil {
+			provider.Annotate(os.Stdout, err)
+		}
+	}
+
 	if info := update.CheckCached(versionShort); info != nil {
 		log.Warn("A new version of bab is available",
 			"latest", info.LatestVersion,
@@ -76,19 +112,53 @@ func (c *CLI) buildCommand() *cobra.Command {
 		Args: cobra.ArbitraryArgs,
 	}
 
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &errs.UsageError{Err: err}
+	})
+
 	cmd.CompletionOptions.DisableDefaultCmd = true
 
 	cmd.PersistentFlags().BoolVarP(&c.verbose, "verbose", "v", false, "Enable verbose output")
 	cmd.PersistentFlags().BoolVarP(&c.dryRun, "dry-run", "n", false, "Show commands without executing")
 	cmd.PersistentFlags().StringVarP(&c.babfile, "babfile", "b", "", "Path to Babfile")
+	cmd.PersistentFlags().BoolVarP(&c.global, "global", "g", false, "Use the global Babfile in the bab config directory")
+	cmd.PersistentFlags().StringVarP(&c.directory, "directory", "C", "", "Run as if bab was started in this directory")
+	cmd.PersistentFlags().StringVarP(&c.profile, "profile", "p", "", "Profile to apply on top of the global vars and env, defaults to $"+profileEnv)
+	_ = cmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	cmd.PersistentFlags().StringVar(&c.ci, "ci", "", "CI output mode (github|gitlab|auto|off), detected from the environment by default")
+	cmd.PersistentFlags().Lookup("ci").NoOptDefVal = "auto"
 	cmd.Flags().BoolVarP(&c.listTasks, "list", "l", false, "List all available tasks")
 	cmd.Flags().BoolVar(&c.validate, "validate", false, "Validate the Babfile without executing tasks")
 	cmd.Flags().StringVarP(&c.completion, "completion", "c", "", "Generate completion script (bash|zsh|fish|powershell)")
+	cmd.Flags().StringVar(&c.report, "report", "", "Write a report of the task run (junit=path.xml)")
+	cmd.Flags().BoolVar(&c.reportCommands, "report-commands", false, "Include each command as a testcase in the report")
+	cmd.Flags().StringArrayVar(&c.answers, "answer", nil, "Answer a prompt without asking (name=value, repeatable)")
+	cmd.Flags().StringVar(&c.answersFile, "answers", "", "Read prompt answers from a YAML file")
+	cmd.Flags().BoolVar(&c.forget, "forget", false, "Clear remembered prompt answers for the Babfile")
+	cmd.Flags().StringVar(&c.platform, "platform", "", "Plan for another platform (os or os/arch), requires --dry-run")
+	cmd.Flags().BoolVarP(&c.recursive, "recursive", "r", false, "Run the task in every Babfile below the current directory that defines it")
+	cmd.Flags().IntVar(&c.maxDepth, "max-depth", -1, "Limit how many directories deep --recursive searches (-1 for no limit)")
+	cmd.Flags().BoolVar(&c.parallel, "parallel", false, "Run the projects found by --recursive in parallel")
+	cmd.Flags().BoolVar(&c.affected, "affected", false, "Only run tasks affected by files changed since --base")
+	cmd.Flags().StringVar(&c.base, "base", "", "Git ref to compare with for --affected and changed(), defaults to origin/HEAD")
+	cmd.Flags().BoolVarP(&c.yes, "yes", "y", false, "Run without asking when the profile requires confirmation")
+	cmd.Flags().StringVar(&c.exitCode, "exit-code", string(runner.ExitCodeFirst), "Exit code to use when parallel commands fail (first|max)")
 
 	return cmd
 }
 
 func (c *CLI) run(cmd *cobra.Command, args []string) error {
+	if c.directory != "" {
+		if err := os.Chdir(c.directory); err != nil {
+			return &errs.UsageError{Err: fmt.Errorf("changing directory: %w", err)}
+		}
+	}
+	if c.global && c.babfile != "" {
+		return &errs.UsageError{Err: errors.New("--global cannot be combined with --babfile")}
+	}
+	if c.profile == "" {
+		c.profile = os.Getenv(profileEnv)
+	}
 	if c.completion != "" {
 		return c.runCompletion(cmd)
 	}
@@ -98,6 +168,20 @@ func (c *CLI) run(cmd *cobra.Command, args []string) error {
 	if c.listTasks {
 		return c.runList()
 	}
+	if c.forget {
+		if err := c.runForget(); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return nil
+		}
+	}
+	if err := c.checkRecursiveFlags(cmd, args); err != nil {
+		return err
+	}
+	if len(args) > 0 && args[0] == includesCommand {
+		return c.runIncludes(args[1:])
+	}
 	if len(args) > 0 {
 		return c.runTask(args[0])
 	}
@@ -105,6 +189,144 @@ func (c *CLI) run(cmd *cobra.Command, args []string) error {
 }
 
 func (c *CLI) runTask(taskName string) error {
+	taskName = c.resolveGlobalTask(taskName)
+
+	provider, err := ci.Resolve(c.ci)
+	if err != nil {
+		return &errs.UsageError{Err: err}
+	}
+
+	exitCode := runner.ExitCodeMode(c.exitCode)
+	if exitCode != "" && !exitCode.Valid() {
+		return &errs.UsageError{Err: fmt.Errorf("invalid exit code mode %q: must be one of: first, max", c.exitCode)}
+	}
+
+	promptAnswers, err := answers.New(c.answers, c.answersFile)
+	if err != nil {
+		return &errs.UsageError{Err: err}
+	}
+
+	if c.platform != "" && !c.dryRun {
+		return &errs.UsageError{Err: errors.New("--platform requires --dry-run")}
+	}
+	platform, err := c.targetPlatform()
+	if err != nil {
+		return err
+	}
+
 	r := runner.New(c.dryRun, c.babfile)
-	return r.Run(c.ctx, taskName)
+	r.Platform = platform
+	r.Version = versionShort
+	r.InvocationDir, _ = os.Getwd()
+	r.Profile = c.profile
+	r.Base = c.base
+	r.Affected = c.affected
+	r.Global = c.global
+	r.Yes = c.yes
+	r.CI = provider
+	r.ExitCode = exitCode
+	r.Answers = promptAnswers
+
+	if memory, err := remember.Load(); err != nil {
+		log.Debug("Remembered answers unavailable", "error", err)
+	} else {
+		r.Memory = memory
+	}
+
+	var reportPath string
+	if c.report != "" {
+		path, err := parseReportSpec(c.report)
+		if err != nil {
+			return &errs.UsageError{Err: err}
+		}
+		reportPath = path
+		r.Report = report.New(c.reportCommands)
+	}
+
+	if c.recursive {
+		err = c.runRecursive(r, taskName)
+	} else {
+		err = r.Run(c.ctx, taskName)
+	}
+	var verrs *errs.ValidationErrors
+	if errors.Is(err, errs.ErrTaskNotFound) && !errors.As(err, &verrs) {
+		err = &errs.UsageError{Err: err}
+	}
+
+	if r.Report != nil {
+		if werr := r.Report.WriteFile(reportPath); werr != nil {
+			return errors.Join(err, werr)
+		}
+		log.Debug("Wrote report", "path", reportPath)
+	}
+
+	return err
+}
+
+func (c *CLI) targetPlatform() (string, error) {
+	if c.platform == "" {
+		return babfile.CurrentPlatform(), nil
+	}
+	platform, err := babfile.ParsePlatformTarget(c.platform)
+	if err != nil {
+		return "", &errs.UsageError{Err: err}
+	}
+	return platform, nil
+}
+
+func (c *CLI) checkRecursiveFlags(cmd *cobra.Command, args []string) error {
+	if !c.recursive {
+		for _, name := range []string{"max-depth", "parallel"} {
+			if cmd.Flags().Changed(name) {
+				return &errs.UsageError{Err: fmt.Errorf("--%s requires --recursive", name)}
+			}
+		}
+		return nil
+	}
+	if len(args) == 0 {
+		return &errs.UsageError{Err: errors.New("--recursive requires a task name")}
+	}
+	if c.babfile != "" || c.global {
+		return &errs.UsageError{Err: errors.New("--recursive cannot be combined with --babfile or --global")}
+	}
+	return nil
+}
+
+func (c *CLI) runRecursive(r *runner.Runner, taskName string) error {
+	paths, err := finder.FindBabfiles(".", c.maxDepth)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("%w in current directory or any subdirectories", errs.ErrBabfileNotFound)
+	}
+	return r.RunRecursive(c.ctx, taskName, paths, c.parallel)
+}
+
+func (c *CLI) loadTasks() (*parser.ParseResult, error) {
+	if c.global {
+		return runner.LoadGlobalTasks()
+	}
+	return runner.LoadTasks(c.babfile)
+}
+
+func (c *CLI) loadTasksWithGlobal() (*parser.ParseResult, error) {
+	if c.global {
+		return runner.LoadGlobalTasks()
+	}
+	return runner.LoadTasksWithGlobal(c.babfile)
+}
+
+func (c *CLI) resolveGlobalTask(taskName string) string {
+	name, ok := strings.CutPrefix(taskName, parser.GlobalNamespace+":")
+	if !ok || c.global || c.recursive {
+		return taskName
+	}
+	if result, err := runner.LoadTasks(c.babfile); err == nil {
+		if _, defined := result.Tasks[taskName]; defined || result.Aliases[taskName] != "" {
+			return taskName
+		}
+	}
+	c.global = true
+	return name
 }
@@ -4,8 +4,13 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/runner"
 )
 
 func TestNewCLI(t *testing.T) {
@@ -106,6 +111,17 @@ func TestCLI_runTask(t *testing.T) {
       - cmd: echo "Hello World"`,
 			wantErr: false,
 		},
+		{
+			name:     "task for another platform",
+			taskName: "elsewhere",
+			babfileYAML: `tasks:
+  elsewhere:
+    platforms: ["!` + runtime.GOOS + `"]
+    run:
+      - cmd: echo "Elsewhere"`,
+			wantErr: true,
+			errMsg:  "is not available on",
+		},
 		{
 			name:     "task not found",
 			taskName: "nonexistent",
@@ -242,3 +258,119 @@ func TestCLI_withCustomBabfile(t *testing.T) {
 		})
 	}
 }
+
+func TestCLI_usageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "unknown flag", args: []string{"--nope"}},
+		{name: "invalid completion shell", args: []string{"--completion", "invalid"}},
+		{name: "invalid exit code mode", args: []string{"--exit-code", "last", "build"}},
+		{name: "unknown includes command", args: []string{"includes", "refresh"}},
+		{name: "platform without dry run", args: []string{"--platform", "linux/arm64", "build"}},
+		{name: "invalid platform", args: []string{"--dry-run", "--platform", "plan9", "build"}},
+		{name: "recursive without task", args: []string{"--recursive"}},
+		{name: "recursive with babfile", args: []string{"-r", "--babfile", "Babfile.yml", "test"}},
+		{name: "parallel without recursive", args: []string{"--parallel", "test"}},
+		{name: "max depth without recursive", args: []string{"--max-depth", "2", "test"}},
+		{name: "global with babfile", args: []string{"-g", "--babfile", "Babfile.yml", "test"}},
+		{name: "missing directory", args: []string{"-C", "does-not-exist", "test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := newCLI()
+			cli.ctx = context.Background()
+			cmd := cli.buildCommand()
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if code := errs.ExitCode(err); code != errs.ExitUsage {
+				t.Errorf("ExitCode() = %d, want %d (err: %v)", code, errs.ExitUsage, err)
+			}
+		})
+	}
+}
+
+func TestCLI_unknownTaskIsUsageError(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "Babfile.yml"), []byte("tasks:\n  build:\n    run:\n      - cmd: echo build\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldDir) })
+
+	cli := newCLI()
+	cli.ctx = context.Background()
+	cmd := cli.buildCommand()
+	cmd.SetArgs([]string{"-C", project, "biuld"})
+	err = cmd.Execute()
+	if code := errs.ExitCode(err); code != errs.ExitUsage || !strings.Contains(err.Error(), `did you mean "build"?`) {
+		t.Errorf("ExitCode() = %d, want %d (err: %v)", code, errs.ExitUsage, err)
+	}
+}
+
+func TestCLI_globalAndDirectory(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	xdg.Reload()
+
+	globalPath := filepath.Join(configHome, "bab", "Babfile.yml")
+	if err := os.MkdirAll(filepath.Dir(globalPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	globalYAML := "tasks:\n  mark:\n    run:\n      - cmd: touch global-mark\n"
+	if err := os.WriteFile(globalPath, []byte(globalYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	projectYAML := "tasks:\n  mark:\n    run:\n      - cmd: touch project-mark\n"
+	if err := os.WriteFile(filepath.Join(project, "Babfile.yml"), []byte(projectYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldDir) })
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-C", project, "mark"}, "project-mark"},
+		{[]string{"-C", project, "-g", "mark"}, "global-mark"},
+		{[]string{"-C", project, "global:mark"}, "global-mark"},
+	} {
+		t.Run(strings.Join(tt.args[2:], " "), func(t *testing.T) {
+			_ = os.Remove(filepath.Join(project, tt.want))
+			cli := newCLI()
+			cli.ctx = context.Background()
+			cmd := cli.buildCommand()
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(project, tt.want)); err != nil {
+				t.Errorf("expected %s in the -C directory: %v", tt.want, err)
+			}
+		})
+	}
+
+	projectPath := filepath.Join(project, "Babfile.yml")
+	if result, err := runner.LoadTasksWithGlobal(projectPath); err != nil || result.Tasks["global:mark"] == nil {
+		t.Errorf("expected global:mark when listing, got %v", err)
+	}
+	if result, err := runner.LoadTasks(projectPath); err != nil || result.Tasks["global:mark"] != nil {
+		t.Errorf("expected LoadTasks() to leave out the global Babfile, got %v", err)
+	}
+	if err := runner.New(false, projectPath).Run(context.Background(), "global:mark"); err == nil {
+		t.Error("expected Runner.Run() not to merge the global Babfile")
+	}
+}
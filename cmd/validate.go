@@ -1,12 +1,11 @@
 package cmd
 
 import (
-	"github.com/bab-sh/bab/internal/runner"
 	"github.com/charmbracelet/log"
 )
 
 func (c *CLI) runValidate() error {
-	result, err := runner.LoadTasks(c.babfile)
+	result, err := c.loadTasks()
 	if err != nil {
 		return err
 	}
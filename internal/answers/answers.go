@@ -0,0 +1,104 @@
+package answers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/errs"
+	"gopkg.in/yaml.v3"
+)
+
+const EnvPrefix = "BAB_ANSWER_"
+
+type Set struct {
+	flags map[string]string
+	file  map[string]string
+}
+
+func New(flags []string, file string) (*Set, error) {
+	s := &Set{flags: make(map[string]string, len(flags))}
+
+	for _, f := range flags {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid answer %q: expected name=value", f)
+		}
+		s.flags[strings.TrimSpace(name)] = value
+	}
+
+	if file != "" {
+		values, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s.file = values
+	}
+
+	return s, nil
+}
+
+func (s *Set) Lookup(name string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	if v, ok := s.flags[name]; ok {
+		return v, true
+	}
+	if v, ok := os.LookupEnv(EnvName(name)); ok {
+		return v, true
+	}
+	if v, ok := s.file[name]; ok {
+		return v, true
+	}
+	return "", false
+}
+
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(name)
+}
+
+func loadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading answers file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, &errs.ParseError{Path: path, Message: "invalid YAML", Cause: err}
+	}
+
+	values := make(map[string]string)
+	if len(root.Content) == 0 {
+		return values, nil
+	}
+
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, &errs.ParseError{Path: path, Line: doc.Line, Message: "answers must be a mapping"}
+	}
+
+	for i := 0; i < len(doc.Content); i += 2 {
+		keyNode := doc.Content[i]
+		valNode := doc.Content[i+1]
+
+		switch valNode.Kind {
+		case yaml.ScalarNode:
+			values[keyNode.Value] = valNode.Value
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(valNode.Content))
+			for _, item := range valNode.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, &errs.ParseError{Path: path, Line: item.Line, Message: fmt.Sprintf("answer %q: list items must be strings", keyNode.Value)}
+				}
+				items = append(items, item.Value)
+			}
+			values[keyNode.Value] = strings.Join(items, ",")
+		default:
+			return nil, &errs.ParseError{Path: path, Line: valNode.Line, Message: fmt.Sprintf("answer %q must be a string or a list", keyNode.Value)}
+		}
+	}
+
+	return values, nil
+}
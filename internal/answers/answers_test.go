@@ -0,0 +1,80 @@
+package answers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "answers.yml")
+	content := "env: staging\nregion: eu\ntargets:\n  - api\n  - web\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BAB_ANSWER_ENV", "dev")
+	t.Setenv("BAB_ANSWER_REGION", "us")
+
+	s, err := New([]string{"env=prod", "note=a=b"}, file)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"env", "prod", true},
+		{"region", "us", true},
+		{"targets", "api,web", true},
+		{"note", "a=b", true},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.Lookup(tt.name)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "nested.yml")
+	if err := os.WriteFile(nested, []byte("env:\n  name: prod\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		flags   []string
+		file    string
+		wantErr string
+	}{
+		{"missing equals", []string{"env"}, "", "expected name=value"},
+		{"empty name", []string{"=prod"}, "", "expected name=value"},
+		{"missing file", nil, filepath.Join(dir, "nope.yml"), "reading answers file"},
+		{"nested mapping", nil, nested, `answer "env" must be a string or a list`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.flags, tt.file)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNilSetLookup(t *testing.T) {
+	var s *Set
+	if _, ok := s.Lookup("env"); ok {
+		t.Error("nil Set should not return answers")
+	}
+}
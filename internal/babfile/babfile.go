@@ -6,18 +6,21 @@ import (
 )
 
 type Schema struct {
-	Vars     VarMap             `json:"vars,omitempty" yaml:"vars,omitempty"`
-	Env      map[string]string  `json:"env,omitempty" yaml:"env,omitempty"`
-	Silent   *bool              `json:"silent,omitempty" yaml:"silent,omitempty"`
-	Output   *bool              `json:"output,omitempty" yaml:"output,omitempty"`
-	Dir      string             `json:"dir,omitempty" yaml:"dir,omitempty"`
-	Includes map[string]Include `json:"includes,omitempty" yaml:"includes,omitempty"`
-	Tasks    map[string]Task    `json:"tasks" yaml:"tasks"`
+	Vars      VarMap             `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env       map[string]string  `json:"env,omitempty" yaml:"env,omitempty"`
+	Silent    *bool              `json:"silent,omitempty" yaml:"silent,omitempty"`
+	Output    *bool              `json:"output,omitempty" yaml:"output,omitempty"`
+	Dir       string             `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Includes  map[string]Include `json:"includes,omitempty" yaml:"includes,omitempty"`
+	Profiles  map[string]Profile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Templates map[string]Task    `json:"templates,omitempty" yaml:"templates,omitempty"`
+	Tasks     map[string]Task    `json:"tasks" yaml:"tasks"`
 }
 
 func (Schema) JSONSchema() *jsonschema.Schema {
 	props := orderedmap.New[string, *jsonschema.Schema]()
 	props.Set("vars", VarsSchema())
+	props.Set("vars_file", VarsFileSchema())
 	props.Set("env", EnvSchema())
 	props.Set("silent", SilentSchema())
 	props.Set("output", OutputSchema())
@@ -31,7 +34,24 @@ func (Schema) JSONSchema() *jsonschema.Schema {
 		AdditionalProperties: &jsonschema.Schema{Ref: "#/$defs/Include"},
 	})
 
+	props.Set("profiles", &jsonschema.Schema{
+		Type:        "object",
+		Description: "Named sets of vars and env, selected with --profile",
+		PropertyNames: &jsonschema.Schema{
+			Pattern: ProfileNamePattern,
+		},
+		AdditionalProperties: &jsonschema.Schema{Ref: "#/$defs/Profile"},
+	})
+
 	minTasks := uint64(1)
+	props.Set("templates", &jsonschema.Schema{
+		Type:        "object",
+		Description: "Task definitions that cannot be run themselves, only extended by tasks",
+		PropertyNames: &jsonschema.Schema{
+			Pattern: TaskNamePattern,
+		},
+		AdditionalProperties: &jsonschema.Schema{Ref: "#/$defs/Task"},
+	})
 	props.Set("tasks", &jsonschema.Schema{
 		Type:          "object",
 		Description:   "Task definitions",
@@ -42,6 +62,15 @@ func (Schema) JSONSchema() *jsonschema.Schema {
 		AdditionalProperties: &jsonschema.Schema{Ref: "#/$defs/Task"},
 	})
 
+	props.Set("override", &jsonschema.Schema{
+		Type:        "object",
+		Description: "Changes to existing tasks, only allowed in Babfile.local.yml",
+		PropertyNames: &jsonschema.Schema{
+			Pattern: TaskNamePattern,
+		},
+		AdditionalProperties: &jsonschema.Schema{Ref: "#/$defs/TaskOverride"},
+	})
+
 	return &jsonschema.Schema{
 		Type:                 "object",
 		Description:          "Babfile configuration",
@@ -49,8 +78,10 @@ func (Schema) JSONSchema() *jsonschema.Schema {
 		Properties:           props,
 		Definitions: jsonschema.Definitions{
 			"Task":              Task{}.JSONSchema(),
+			"TaskOverride":      TaskOverrideSchema(),
 			"TaskName":          TaskNameSchema(),
 			"Include":           Include{}.JSONSchema(),
+			"Profile":           Profile{}.JSONSchema(),
 			"Platform":          Platform("").JSONSchema(),
 			"RunItem":           RunItemSchema(),
 			"ParallelChildItem": ParallelChildItemSchema(),
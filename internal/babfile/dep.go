@@ -0,0 +1,68 @@
+package babfile
+
+import (
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type Dep struct {
+	Line int               `json:"-" yaml:"-"`
+	Task string            `json:"task" yaml:"task"`
+	Vars VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env  map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+func DepNames(deps []Dep) []string {
+	names := make([]string, len(deps))
+	for i, dep := range deps {
+		names[i] = dep.Task
+	}
+	return names
+}
+
+func DepsSchema() *jsonschema.Schema {
+	minLen := uint64(1)
+	props := orderedmap.New[string, *jsonschema.Schema]()
+	props.Set("task", &jsonschema.Schema{
+		Type:        "string",
+		MinLength:   &minLen,
+		Pattern:     TaskNamePattern,
+		Description: "Task name to depend on",
+	})
+	props.Set("vars", CallVarsSchema())
+	props.Set("env", CallEnvSchema())
+
+	return &jsonschema.Schema{
+		Type:        "array",
+		Description: "Tasks to run first",
+		Items: &jsonschema.Schema{
+			OneOf: []*jsonschema.Schema{
+				{
+					Type:        "string",
+					Pattern:     TaskNamePattern,
+					Description: "Task name to depend on",
+				},
+				{
+					Type:                 "object",
+					Description:          "Task to depend on, called with vars and env",
+					Required:             []string{"task"},
+					AdditionalProperties: jsonschema.FalseSchema,
+					Properties:           props,
+				},
+			},
+		},
+		UniqueItems: true,
+	}
+}
+
+func CallVarsSchema() *jsonschema.Schema {
+	schema := VarsSchema()
+	schema.Description = "Variables passed to the task, overriding its own vars"
+	return schema
+}
+
+func CallEnvSchema() *jsonschema.Schema {
+	schema := EnvSchema()
+	schema.Description = "Environment variables passed to the task"
+	return schema
+}
@@ -0,0 +1,13 @@
+package babfile
+
+import "github.com/invopop/jsonschema"
+
+func ForSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Description: "Run the command once per item of a list or a ${{ var }} reference. The current item is available as ${{ item }} and its position as ${{ index }}.",
+		AnyOf: []*jsonschema.Schema{
+			{Type: "array"},
+			{Type: "string", Pattern: `\$\{\{.+\}\}`},
+		},
+	}
+}
@@ -5,25 +5,126 @@ import (
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
 
+const DefaultIncludePath = "Babfile.yml"
+
 type Include struct {
-	Babfile string `json:"babfile" yaml:"babfile"`
+	Babfile  string            `json:"babfile,omitempty" yaml:"babfile,omitempty"`
+	Glob     string            `json:"glob,omitempty" yaml:"glob,omitempty"`
+	Git      string            `json:"git,omitempty" yaml:"git,omitempty"`
+	Ref      string            `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Path     string            `json:"path,omitempty" yaml:"path,omitempty"`
+	Vars     VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env      map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Dir      string            `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Optional bool              `json:"optional,omitempty" yaml:"optional,omitempty"`
+	Flatten  bool              `json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	Aliases  []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+func (i Include) IsRemote() bool {
+	return i.Git != ""
+}
+
+func (i Include) RemotePath() string {
+	if i.Path == "" {
+		return DefaultIncludePath
+	}
+	return i.Path
 }
 
 func (Include) JSONSchema() *jsonschema.Schema {
 	minLen := uint64(1)
-	props := orderedmap.New[string, *jsonschema.Schema]()
-	props.Set("babfile", &jsonschema.Schema{
+	local := orderedmap.New[string, *jsonschema.Schema]()
+	local.Set("babfile", &jsonschema.Schema{
 		Type:        "string",
 		MinLength:   &minLen,
 		Pattern:     ".*[Bb]abfile(\\..*)?\\.(ya?ml)$",
 		Description: "Path to babfile",
 	})
+	setIncludeOptions(local)
+
+	glob := orderedmap.New[string, *jsonschema.Schema]()
+	glob.Set("glob", &jsonschema.Schema{
+		Type:        "string",
+		MinLength:   &minLen,
+		Description: "Pattern matching babfiles, each match is included under a namespace named after its directory",
+	})
+	setIncludeOptions(glob)
+	glob.Delete("dir")
+
+	remote := orderedmap.New[string, *jsonschema.Schema]()
+	remote.Set("git", &jsonschema.Schema{
+		Type:        "string",
+		MinLength:   &minLen,
+		Description: "Git repository URL",
+	})
+	remote.Set("ref", &jsonschema.Schema{
+		Type:        "string",
+		MinLength:   &minLen,
+		Description: "Branch, tag or commit to use, defaults to the default branch",
+	})
+	remote.Set("path", &jsonschema.Schema{
+		Type:        "string",
+		MinLength:   &minLen,
+		Pattern:     ".*[Bb]abfile(\\..*)?\\.(ya?ml)$",
+		Default:     DefaultIncludePath,
+		Description: "Path to the babfile inside the repository",
+	})
+	setIncludeOptions(remote)
 
 	return &jsonschema.Schema{
-		Type:                 "object",
-		Description:          "External babfile reference",
-		Required:             []string{"babfile"},
-		AdditionalProperties: jsonschema.FalseSchema,
-		Properties:           props,
+		Description: "External babfile reference",
+		OneOf: []*jsonschema.Schema{
+			{
+				Type:                 "object",
+				Required:             []string{"babfile"},
+				AdditionalProperties: jsonschema.FalseSchema,
+				Properties:           local,
+			},
+			{
+				Type:                 "object",
+				Required:             []string{"glob"},
+				AdditionalProperties: jsonschema.FalseSchema,
+				Properties:           glob,
+			},
+			{
+				Type:                 "object",
+				Required:             []string{"git"},
+				AdditionalProperties: jsonschema.FalseSchema,
+				Properties:           remote,
+			},
+		},
 	}
 }
+
+func setIncludeOptions(props *orderedmap.OrderedMap[string, *jsonschema.Schema]) {
+	vars := VarsSchema()
+	vars.Description = "Variables overriding the included babfile's global vars"
+	props.Set("vars", vars)
+	env := EnvSchema()
+	env.Description = "Environment variables overriding the included babfile's global env"
+	props.Set("env", env)
+	props.Set("dir", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Directory the included tasks run in and resolve relative dirs against",
+	})
+	props.Set("optional", &jsonschema.Schema{
+		Type:        "boolean",
+		Default:     false,
+		Description: "Skip the include when the babfile does not exist or the glob matches nothing",
+	})
+	props.Set("flatten", &jsonschema.Schema{
+		Type:        "boolean",
+		Default:     false,
+		Description: "Import tasks without the namespace prefix",
+	})
+	props.Set("aliases", &jsonschema.Schema{
+		Type:        "array",
+		Description: "Alternative namespaces for the included tasks",
+		Items: &jsonschema.Schema{
+			Type:    "string",
+			Pattern: TaskNamePattern,
+		},
+		UniqueItems: true,
+	})
+}
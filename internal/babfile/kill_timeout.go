@@ -0,0 +1,19 @@
+package babfile
+
+import (
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+const DefaultKillTimeout = 10 * time.Second
+
+const KillTimeoutPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+func KillTimeoutSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Pattern:     KillTimeoutPattern,
+		Description: "Total grace period after an interrupt before SIGKILL, with SIGTERM sent halfway (e.g., '10s', '1m30s'). Defaults to 10s.",
+	}
+}
@@ -1,6 +1,13 @@
 package babfile
 
-import "github.com/invopop/jsonschema"
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
 
 type Platform string
 
@@ -8,17 +15,76 @@ const (
 	PlatformLinux   Platform = "linux"
 	PlatformDarwin  Platform = "darwin"
 	PlatformWindows Platform = "windows"
+	PlatformFreeBSD Platform = "freebsd"
+	PlatformOpenBSD Platform = "openbsd"
 )
 
-var ValidPlatforms = []Platform{PlatformLinux, PlatformDarwin, PlatformWindows}
+var ValidPlatforms = []Platform{PlatformLinux, PlatformDarwin, PlatformWindows, PlatformFreeBSD, PlatformOpenBSD}
+
+var ValidArchs = []string{"amd64", "arm64", "386", "arm", "riscv64", "ppc64le", "s390x"}
+
+func PlatformPattern() string {
+	return fmt.Sprintf(`^!?(\*|%s)(/(\*|%s))?$`, strings.Join(PlatformNames(), "|"), strings.Join(ValidArchs, "|"))
+}
+
+func CurrentPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+func ParsePlatformTarget(s string) (string, error) {
+	return parsePlatformTarget(s, runtime.GOARCH)
+}
+
+func parsePlatformTarget(s, hostArch string) (string, error) {
+	goos, goarch, hasArch := strings.Cut(s, "/")
+	if !slices.Contains(ValidPlatforms, Platform(goos)) {
+		return "", fmt.Errorf("invalid platform %q: OS must be one of: %s", s, strings.Join(PlatformNames(), ", "))
+	}
+	if !hasArch {
+		return goos + "/" + hostArch, nil
+	}
+	if !slices.Contains(ValidArchs, goarch) {
+		return "", fmt.Errorf("invalid platform %q: architecture must be one of: %s", s, strings.Join(ValidArchs, ", "))
+	}
+	return goos + "/" + goarch, nil
+}
+
+func PlatformNames() []string {
+	names := make([]string, len(ValidPlatforms))
+	for i, p := range ValidPlatforms {
+		names[i] = string(p)
+	}
+	return names
+}
+
+func (p Platform) parts() (negated bool, goos, goarch string) {
+	s := string(p)
+	negated = strings.HasPrefix(s, "!")
+	s = strings.TrimPrefix(s, "!")
+	goos, goarch, hasArch := strings.Cut(s, "/")
+	if !hasArch {
+		goarch = "*"
+	}
+	return negated, goos, goarch
+}
 
 func (p Platform) Valid() bool {
-	for _, v := range ValidPlatforms {
-		if p == v {
-			return true
-		}
+	_, goos, goarch := p.parts()
+	if goos != "*" && !slices.Contains(ValidPlatforms, Platform(goos)) {
+		return false
 	}
-	return false
+	return goarch == "*" || slices.Contains(ValidArchs, goarch)
+}
+
+func (p Platform) Negated() bool {
+	negated, _, _ := p.parts()
+	return negated
+}
+
+func (p Platform) Matches(target string) bool {
+	_, goos, goarch := p.parts()
+	targetOS, targetArch, _ := strings.Cut(target, "/")
+	return (goos == "*" || goos == targetOS) && (goarch == "*" || goarch == targetArch)
 }
 
 func (p Platform) String() string {
@@ -26,14 +92,10 @@ func (p Platform) String() string {
 }
 
 func (Platform) JSONSchema() *jsonschema.Schema {
-	enumValues := make([]any, len(ValidPlatforms))
-	for i, p := range ValidPlatforms {
-		enumValues[i] = string(p)
-	}
 	return &jsonschema.Schema{
 		Type:        "string",
-		Enum:        enumValues,
-		Description: "Target operating system",
+		Pattern:     PlatformPattern(),
+		Description: "Target platform as os, os/arch or */arch, prefix with ! to exclude",
 	}
 }
 
@@ -45,3 +107,9 @@ func PlatformsArraySchema() *jsonschema.Schema {
 		UniqueItems: true,
 	}
 }
+
+func TaskPlatformsSchema() *jsonschema.Schema {
+	schema := PlatformsArraySchema()
+	schema.Description = "Platforms the task is available on, other platforms hide it"
+	return schema
+}
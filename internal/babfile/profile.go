@@ -0,0 +1,51 @@
+package babfile
+
+import (
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+const ProfileNamePattern = "^[a-zA-Z0-9_-]+$"
+
+type Profile struct {
+	Desc    string            `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Vars    VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Dotenv  []string          `json:"dotenv,omitempty" yaml:"dotenv,omitempty"`
+	Confirm bool              `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+}
+
+func (Profile) JSONSchema() *jsonschema.Schema {
+	minLen := uint64(1)
+	props := orderedmap.New[string, *jsonschema.Schema]()
+	props.Set("desc", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Profile description",
+	})
+	vars := VarsSchema()
+	vars.Description = "Variables overriding the global vars while the profile is active"
+	props.Set("vars", vars)
+	env := EnvSchema()
+	env.Description = "Environment variables overriding the global env while the profile is active"
+	props.Set("env", env)
+	props.Set("dotenv", &jsonschema.Schema{
+		Type:        "array",
+		Description: "Dotenv files loaded into the environment, relative to the babfile",
+		Items: &jsonschema.Schema{
+			Type:      "string",
+			MinLength: &minLen,
+		},
+	})
+	props.Set("confirm", &jsonschema.Schema{
+		Type:        "boolean",
+		Default:     false,
+		Description: "Ask for confirmation before running tasks with this profile",
+	})
+
+	return &jsonschema.Schema{
+		Type:                 "object",
+		Description:          "Environment profile selected with --profile or BAB_PROFILE",
+		AdditionalProperties: jsonschema.FalseSchema,
+		Properties:           props,
+	}
+}
@@ -32,13 +32,18 @@ func ParallelChildItemSchema() *jsonschema.Schema {
 }
 
 func matchesPlatform(platforms []Platform, platform string) bool {
-	if len(platforms) == 0 {
-		return true
-	}
+	included, hasIncludes := false, false
 	for _, p := range platforms {
-		if string(p) == platform {
-			return true
+		if p.Negated() {
+			if p.Matches(platform) {
+				return false
+			}
+			continue
+		}
+		hasIncludes = true
+		if p.Matches(platform) {
+			included = true
 		}
 	}
-	return false
+	return included || !hasIncludes
 }
@@ -1,19 +1,23 @@
 package babfile
 
 import (
+	"time"
+
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
 
 type CommandRun struct {
-	Line      int               `json:"-" yaml:"-"`
-	Cmd       string            `json:"cmd" yaml:"cmd"`
-	Dir       string            `json:"dir,omitempty" yaml:"dir,omitempty"`
-	Env       map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
-	Silent    *bool             `json:"silent,omitempty" yaml:"silent,omitempty"`
-	Output    *bool             `json:"output,omitempty" yaml:"output,omitempty"`
-	Platforms []Platform        `json:"platforms,omitempty" yaml:"platforms,omitempty"`
-	When      string            `json:"when,omitempty" yaml:"when,omitempty"`
+	Line        int               `json:"-" yaml:"-"`
+	Cmd         string            `json:"cmd" yaml:"cmd"`
+	Dir         string            `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Env         map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Silent      *bool             `json:"silent,omitempty" yaml:"silent,omitempty"`
+	Output      *bool             `json:"output,omitempty" yaml:"output,omitempty"`
+	Platforms   []Platform        `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	When        string            `json:"when,omitempty" yaml:"when,omitempty"`
+	KillTimeout time.Duration     `json:"kill_timeout,omitempty" yaml:"kill_timeout,omitempty"`
+	For         any               `json:"for,omitempty" yaml:"for,omitempty"`
 }
 
 func (CommandRun) isRunItem() {}
@@ -42,6 +46,8 @@ func CommandRunSchema() *jsonschema.Schema {
 	props.Set("output", OutputSchema())
 	props.Set("platforms", PlatformsArraySchema())
 	props.Set("when", WhenSchema())
+	props.Set("kill_timeout", KillTimeoutSchema())
+	props.Set("for", ForSchema())
 	props.Set("label", LabelSchema())
 
 	return &jsonschema.Schema{
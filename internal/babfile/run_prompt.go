@@ -14,6 +14,17 @@ const (
 	PromptTypeMultiselect PromptType = "multiselect"
 	PromptTypePassword    PromptType = "password"
 	PromptTypeNumber      PromptType = "number"
+	PromptTypeText        PromptType = "text"
+	PromptTypePath        PromptType = "path"
+	PromptTypeDate        PromptType = "date"
+	PromptTypeTime        PromptType = "time"
+)
+
+const (
+	PromptDateLayout = "2006-01-02"
+	PromptTimeLayout = "15:04"
+	PromptDateToday  = "today"
+	PromptTimeNow    = "now"
 )
 
 var ValidPromptTypes = []PromptType{
@@ -23,6 +34,10 @@ var ValidPromptTypes = []PromptType{
 	PromptTypeMultiselect,
 	PromptTypePassword,
 	PromptTypeNumber,
+	PromptTypeText,
+	PromptTypePath,
+	PromptTypeDate,
+	PromptTypeTime,
 }
 
 func (p PromptType) Valid() bool {
@@ -50,7 +65,7 @@ func PromptTypeSchema() *jsonschema.Schema {
 	return &jsonschema.Schema{
 		Type:        "string",
 		Enum:        enumValues,
-		Description: "Prompt type (confirm, input, select, multiselect, password, number)",
+		Description: "Prompt type (confirm, input, select, multiselect, password, number, text, path, date, time)",
 	}
 }
 
@@ -65,9 +80,12 @@ type PromptRun struct {
 	Options     []string   `json:"options,omitempty" yaml:"options,omitempty"`
 	Placeholder string     `json:"placeholder,omitempty" yaml:"placeholder,omitempty"`
 	Validate    string     `json:"validate,omitempty" yaml:"validate,omitempty"`
+	Glob        string     `json:"glob,omitempty" yaml:"glob,omitempty"`
 	Min         *int       `json:"min,omitempty" yaml:"min,omitempty"`
 	Max         *int       `json:"max,omitempty" yaml:"max,omitempty"`
 	Confirm     *bool      `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+	Defer       bool       `json:"defer,omitempty" yaml:"defer,omitempty"`
+	Remember    bool       `json:"remember,omitempty" yaml:"remember,omitempty"`
 	When        string     `json:"when,omitempty" yaml:"when,omitempty"`
 }
 
@@ -105,7 +123,7 @@ func PromptRunSchema() *jsonschema.Schema {
 			{Type: "string"},
 			{Type: "boolean"},
 		},
-		Description: "Default value for the prompt",
+		Description: "Default value for the prompt. Date prompts accept 'today' and time prompts accept 'now'.",
 	})
 	props.Set("defaults", &jsonschema.Schema{
 		Type:        "array",
@@ -124,7 +142,11 @@ func PromptRunSchema() *jsonschema.Schema {
 	})
 	props.Set("validate", &jsonschema.Schema{
 		Type:        "string",
-		Description: "Regex pattern to validate input",
+		Description: "Regex pattern to validate input or text",
+	})
+	props.Set("glob", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Glob pattern that selected files must match for path prompts (e.g., '*.sql')",
 	})
 	props.Set("min", &jsonschema.Schema{
 		Type:        "integer",
@@ -138,6 +160,14 @@ func PromptRunSchema() *jsonschema.Schema {
 		Type:        "boolean",
 		Description: "Require password confirmation (re-entry)",
 	})
+	props.Set("defer", &jsonschema.Schema{
+		Type:        "boolean",
+		Description: "Ask when the prompt is reached instead of in the form shown before the task runs",
+	})
+	props.Set("remember", &jsonschema.Schema{
+		Type:        "boolean",
+		Description: "Offer the last answer as the default next time (not allowed for password prompts)",
+	})
 	props.Set("platforms", PlatformsArraySchema())
 	props.Set("when", WhenSchema())
 
@@ -6,12 +6,14 @@ import (
 )
 
 type TaskRun struct {
-	Line      int        `json:"-" yaml:"-"`
-	Task      string     `json:"task" yaml:"task"`
-	Silent    *bool      `json:"silent,omitempty" yaml:"silent,omitempty"`
-	Output    *bool      `json:"output,omitempty" yaml:"output,omitempty"`
-	Platforms []Platform `json:"platforms,omitempty" yaml:"platforms,omitempty"`
-	When      string     `json:"when,omitempty" yaml:"when,omitempty"`
+	Line      int               `json:"-" yaml:"-"`
+	Task      string            `json:"task" yaml:"task"`
+	Vars      VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env       map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Silent    *bool             `json:"silent,omitempty" yaml:"silent,omitempty"`
+	Output    *bool             `json:"output,omitempty" yaml:"output,omitempty"`
+	Platforms []Platform        `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	When      string            `json:"when,omitempty" yaml:"when,omitempty"`
 }
 
 func (TaskRun) isRunItem() {}
@@ -35,6 +37,8 @@ func TaskRunSchema() *jsonschema.Schema {
 		Pattern:     TaskNamePattern,
 		Description: "Task reference",
 	})
+	props.Set("vars", CallVarsSchema())
+	props.Set("env", CallEnvSchema())
 	props.Set("silent", SilentSchema())
 	props.Set("output", OutputSchema())
 	props.Set("platforms", PlatformsArraySchema())
@@ -1,6 +1,9 @@
 package babfile
 
 import (
+	"path/filepath"
+	"time"
+
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
@@ -8,21 +11,29 @@ import (
 const TaskNamePattern = "^[a-zA-Z0-9_-]+(:[a-zA-Z0-9_-]+)*$"
 
 type Task struct {
-	Name       string            `json:"-" yaml:"-"`
-	Line       int               `json:"-" yaml:"-"`
-	DepsLine   int               `json:"-" yaml:"-"`
-	SourcePath string            `json:"-" yaml:"-"`
-	Desc       string            `json:"desc,omitempty" yaml:"desc,omitempty"`
-	Alias      string            `json:"alias,omitempty" yaml:"alias,omitempty"`
-	Aliases    []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
-	Vars       VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
-	Env        map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
-	Silent     *bool             `json:"silent,omitempty" yaml:"silent,omitempty"`
-	Output     *bool             `json:"output,omitempty" yaml:"output,omitempty"`
-	Dir        string            `json:"dir,omitempty" yaml:"dir,omitempty"`
-	When       string            `json:"when,omitempty" yaml:"when,omitempty"`
-	Deps       []string          `json:"deps,omitempty" yaml:"deps,omitempty"`
-	Run        []RunItem         `json:"-" yaml:"-"`
+	Name        string            `json:"-" yaml:"-"`
+	Line        int               `json:"-" yaml:"-"`
+	DepsLine    int               `json:"-" yaml:"-"`
+	SourcePath  string            `json:"-" yaml:"-"`
+	BaseDir     string            `json:"-" yaml:"-"`
+	Local       bool              `json:"-" yaml:"-"`
+	ExtendsLine int               `json:"-" yaml:"-"`
+	Extends     string            `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Desc        string            `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Alias       string            `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Vars        VarMap            `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Env         map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Silent      *bool             `json:"silent,omitempty" yaml:"silent,omitempty"`
+	Output      *bool             `json:"output,omitempty" yaml:"output,omitempty"`
+	Dir         string            `json:"dir,omitempty" yaml:"dir,omitempty"`
+	When        string            `json:"when,omitempty" yaml:"when,omitempty"`
+	KillTimeout time.Duration     `json:"kill_timeout,omitempty" yaml:"kill_timeout,omitempty"`
+	Platforms   []Platform        `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	Sources     []string          `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Deps        []Dep             `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Run         []RunItem         `json:"-" yaml:"-"`
+	RunAppend   []RunItem         `json:"-" yaml:"-"`
 }
 
 func (t *Task) GetAllAliases() []string {
@@ -36,6 +47,17 @@ func (t *Task) GetAllAliases() []string {
 	return append(aliases, t.Aliases...)
 }
 
+func (t *Task) Root() string {
+	if t.BaseDir != "" {
+		return t.BaseDir
+	}
+	return filepath.Dir(t.SourcePath)
+}
+
+func (t *Task) ShouldRunOnPlatform(platform string) bool {
+	return matchesPlatform(t.Platforms, platform)
+}
+
 type TaskMap map[string]*Task
 
 func (tm TaskMap) Has(name string) bool {
@@ -51,8 +73,19 @@ func (tm TaskMap) Names() []string {
 	return names
 }
 
+func (tm TaskMap) ForPlatform(platform string) TaskMap {
+	filtered := make(TaskMap, len(tm))
+	for name, task := range tm {
+		if task == nil || task.ShouldRunOnPlatform(platform) {
+			filtered[name] = task
+		}
+	}
+	return filtered
+}
+
 func (Task) JSONSchema() *jsonschema.Schema {
 	minRunItems := uint64(1)
+	minSources := uint64(1)
 	props := orderedmap.New[string, *jsonschema.Schema]()
 	props.Set("desc", &jsonschema.Schema{
 		Type:        "string",
@@ -72,12 +105,29 @@ func (Task) JSONSchema() *jsonschema.Schema {
 		},
 		UniqueItems: true,
 	})
+	props.Set("extends", &jsonschema.Schema{
+		Type:        "string",
+		Pattern:     TaskNamePattern,
+		Description: "Task or template to inherit vars, env, dir, deps, silent, output and run from",
+	})
 	props.Set("vars", VarsSchema())
+	props.Set("vars_file", VarsFileSchema())
 	props.Set("env", EnvSchema())
 	props.Set("silent", SilentSchema())
 	props.Set("output", OutputSchema())
 	props.Set("dir", DirSchema())
 	props.Set("when", WhenSchema())
+	props.Set("kill_timeout", KillTimeoutSchema())
+	props.Set("platforms", TaskPlatformsSchema())
+	props.Set("sources", &jsonschema.Schema{
+		Type:        "array",
+		Description: "Files the task depends on, as globs relative to its directory, used by --affected",
+		Items: &jsonschema.Schema{
+			Type:      "string",
+			MinLength: &minSources,
+		},
+		UniqueItems: true,
+	})
 	props.Set("deps", DepsSchema())
 	props.Set("run", &jsonschema.Schema{
 		Type:        "array",
@@ -85,6 +135,11 @@ func (Task) JSONSchema() *jsonschema.Schema {
 		MinItems:    &minRunItems,
 		Items:       &jsonschema.Schema{Ref: "#/$defs/RunItem"},
 	})
+	props.Set("run_append", &jsonschema.Schema{
+		Type:        "array",
+		Description: "Commands or tasks to run after the inherited ones, requires extends",
+		Items:       &jsonschema.Schema{Ref: "#/$defs/RunItem"},
+	})
 
 	return &jsonschema.Schema{
 		Type:                 "object",
@@ -94,17 +149,19 @@ func (Task) JSONSchema() *jsonschema.Schema {
 	}
 }
 
-func DepsSchema() *jsonschema.Schema {
-	return &jsonschema.Schema{
+func TaskOverrideSchema() *jsonschema.Schema {
+	schema := Task{}.JSONSchema()
+	schema.Description = "Fields replacing those of an existing task, vars and env are merged"
+	schema.Properties.Delete("extends")
+	depsAppend := DepsSchema()
+	depsAppend.Description = "Tasks to add to the existing deps"
+	schema.Properties.Set("deps_append", depsAppend)
+	schema.Properties.Set("run_append", &jsonschema.Schema{
 		Type:        "array",
-		Description: "Tasks to run first",
-		Items: &jsonschema.Schema{
-			Type:        "string",
-			Pattern:     TaskNamePattern,
-			Description: "Task name to depend on",
-		},
-		UniqueItems: true,
-	}
+		Description: "Commands or tasks to run after the existing ones",
+		Items:       &jsonschema.Schema{Ref: "#/$defs/RunItem"},
+	})
+	return schema
 }
 
 func TaskNameSchema() *jsonschema.Schema {
@@ -2,6 +2,7 @@ package babfile
 
 import (
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -13,7 +14,13 @@ func TestPlatformValid(t *testing.T) {
 		{PlatformLinux, true},
 		{PlatformDarwin, true},
 		{PlatformWindows, true},
-		{Platform("freebsd"), false},
+		{PlatformFreeBSD, true},
+		{Platform("linux/arm64"), true},
+		{Platform("*/amd64"), true},
+		{Platform("!windows"), true},
+		{Platform("plan9"), false},
+		{Platform("linux/sparc"), false},
+		{Platform("!"), false},
 		{Platform(""), false},
 	}
 	for _, tt := range tests {
@@ -25,6 +32,23 @@ func TestPlatformValid(t *testing.T) {
 	}
 }
 
+func TestParsePlatformTarget(t *testing.T) {
+	if got, err := ParsePlatformTarget("linux/arm64"); err != nil || got != "linux/arm64" {
+		t.Errorf("ParsePlatformTarget(linux/arm64) = %q, %v", got, err)
+	}
+	if got, err := ParsePlatformTarget("windows"); err != nil || !strings.HasPrefix(got, "windows/") {
+		t.Errorf("ParsePlatformTarget(windows) = %q, %v", got, err)
+	}
+	if got, err := parsePlatformTarget("linux", "loong64"); err != nil || got != "linux/loong64" {
+		t.Errorf("parsePlatformTarget(linux) on loong64 = %q, %v", got, err)
+	}
+	for _, s := range []string{"*/amd64", "!linux", "linux/sparc", ""} {
+		if _, err := ParsePlatformTarget(s); err == nil {
+			t.Errorf("ParsePlatformTarget(%q) expected error", s)
+		}
+	}
+}
+
 func TestPlatformString(t *testing.T) {
 	tests := []struct {
 		platform Platform
@@ -186,6 +210,15 @@ func TestMatchesPlatform(t *testing.T) {
 		{"no match", []Platform{PlatformLinux}, "darwin", false},
 		{"multi with one match", []Platform{PlatformLinux, PlatformDarwin}, "darwin", true},
 		{"multi no match", []Platform{PlatformLinux, PlatformWindows}, "darwin", false},
+		{"os matches any arch", []Platform{"linux"}, "linux/arm64", true},
+		{"arch mismatch", []Platform{"linux/arm64"}, "linux/amd64", false},
+		{"wildcard os", []Platform{"*/amd64"}, "darwin/amd64", true},
+		{"wildcard os arch mismatch", []Platform{"*/amd64"}, "darwin/arm64", false},
+		{"negation only", []Platform{"!windows"}, "linux/amd64", true},
+		{"negation excludes", []Platform{"!windows"}, "windows/amd64", false},
+		{"negation wins", []Platform{"linux", "darwin", "!linux/386"}, "linux/386", false},
+		{"negation with include", []Platform{"linux", "darwin", "!linux/386"}, "darwin/arm64", true},
+		{"negation without include match", []Platform{"darwin", "!linux/386"}, "linux/amd64", false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -4,7 +4,7 @@ import "github.com/invopop/jsonschema"
 
 const VarNamePattern = "^[a-zA-Z_][a-zA-Z0-9_]*$"
 
-type VarMap map[string]string
+type VarMap map[string]any
 
 func MergeVarMaps(varMaps ...VarMap) VarMap {
 	merged := make(VarMap)
@@ -19,13 +19,41 @@ func MergeVarMaps(varMaps ...VarMap) VarMap {
 func VarsSchema() *jsonschema.Schema {
 	return &jsonschema.Schema{
 		Type:        "object",
-		Description: "Variables as key-value pairs. Values can reference other variables or environment variables using ${{ var }} or ${{ env.VAR }} syntax.",
+		Description: "Variables as key-value pairs. Values can be strings, lists or maps and can reference other variables or environment variables using ${{ var }} or ${{ env.VAR }} syntax.",
 		PropertyNames: &jsonschema.Schema{
 			Pattern:     VarNamePattern,
 			Description: "Variable name (alphanumeric and underscores, must start with letter or underscore)",
 		},
-		AdditionalProperties: &jsonschema.Schema{
-			Type: "string",
+		AdditionalProperties: VarValueSchema(),
+	}
+}
+
+func VarsFileSchema() *jsonschema.Schema {
+	minLen := uint64(1)
+	path := &jsonschema.Schema{
+		Type:      "string",
+		MinLength: &minLen,
+	}
+	return &jsonschema.Schema{
+		Description: "YAML, JSON or dotenv files to load vars from, relative to the babfile. Append #key.path to load a nested mapping or a single value. Values from vars win over loaded ones.",
+		OneOf: []*jsonschema.Schema{
+			path,
+			{
+				Type:  "array",
+				Items: path,
+			},
+		},
+	}
+}
+
+func VarValueSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		AnyOf: []*jsonschema.Schema{
+			{Type: "string"},
+			{Type: "number"},
+			{Type: "boolean"},
+			{Type: "array", Description: "List, accessed with ${{ name[0] }}"},
+			{Type: "object", Description: "Map, accessed with ${{ name.key }}"},
 		},
 	}
 }
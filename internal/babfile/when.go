@@ -5,6 +5,6 @@ import "github.com/invopop/jsonschema"
 func WhenSchema() *jsonschema.Schema {
 	return &jsonschema.Schema{
 		Type:        "string",
-		Description: "Condition expression. Supports: ${{ var }}, ${{ var }} == 'value', ${{ var }} != 'value'",
+		Description: "Condition expression. Supports ${{ var }}, ==, !=, <, <=, >, >=, =~, in [...], &&, ||, !, parentheses and the functions exists(), env() and has_cmd()",
 	}
 }
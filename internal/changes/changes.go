@@ -0,0 +1,108 @@
+package changes
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/bab-sh/bab/internal/git"
+	"github.com/bab-sh/bab/internal/glob"
+	"github.com/charmbracelet/log"
+)
+
+type Set struct {
+	files []string
+}
+
+var (
+	mu   sync.Mutex
+	sets = make(map[string]*Set)
+)
+
+func For(dir, base string) (*Set, error) {
+	top, err := git.TopLevel(dir)
+	if err != nil {
+		return nil, fmt.Errorf("finding git repository of %s: %w", dir, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key := top + "@" + base
+	if s, ok := sets[key]; ok {
+		return s, nil
+	}
+	s, err := load(top, base)
+	if err != nil {
+		return nil, err
+	}
+	sets[key] = s
+	return s, nil
+}
+
+func load(top, base string) (*Set, error) {
+	if base == "" {
+		base = defaultBase(top)
+	}
+	commit := base
+	if base != "HEAD" {
+		mergeBase, err := git.MergeBase(top, base, "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("comparing with %s: %w", base, err)
+		}
+		commit = mergeBase
+	}
+
+	files, err := git.ChangedFiles(top, commit)
+	if err != nil {
+		return nil, fmt.Errorf("listing changed files: %w", err)
+	}
+	s := &Set{files: make([]string, len(files))}
+	for i, f := range files {
+		s.files[i] = filepath.Join(top, filepath.FromSlash(f))
+	}
+	log.Debug("Loaded changed files", "base", base, "commit", commit, "count", len(files))
+	return s, nil
+}
+
+func defaultBase(top string) string {
+	if _, err := git.ResolveCommit(top, "origin/HEAD"); err == nil {
+		return "origin/HEAD"
+	}
+	return "HEAD"
+}
+
+func (s *Set) Under(dir string) bool {
+	dir = realPath(dir)
+	for _, f := range s.files {
+		if rel, err := filepath.Rel(dir, f); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Set) Match(dir string, patterns []string) bool {
+	dir = realPath(dir)
+	for _, f := range s.files {
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			continue
+		}
+		for _, pattern := range patterns {
+			if glob.Match(filepath.ToSlash(filepath.Clean(pattern)), filepath.ToSlash(rel)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func realPath(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return dir
+}
@@ -0,0 +1,101 @@
+package changes
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupRepo(t *testing.T) (dir, base string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	gitRun := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=bab", "-c", "user.email=bab@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitRun("init", "-q", "-b", "main")
+	write("api/src/main.go")
+	write("web/src/app.ts")
+	write("lib/util.go")
+	gitRun("add", "-A")
+	gitRun("commit", "-q", "-m", "base")
+	base = gitRun("rev-parse", "HEAD")
+
+	gitRun("checkout", "-q", "-b", "feature")
+	write("api/src/handler.go")
+	gitRun("add", "-A")
+	gitRun("commit", "-q", "-m", "change api")
+	write("lib/README.md")
+	return dir, base
+}
+
+func TestFor(t *testing.T) {
+	dir, base := setupRepo(t)
+
+	s, err := For(filepath.Join(dir, "web"), base)
+	if err != nil {
+		t.Fatalf("For() error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"committed change under api", s.Under(filepath.Join(dir, "api")), true},
+		{"untracked file under lib", s.Under(filepath.Join(dir, "lib")), true},
+		{"nothing under web", s.Under(filepath.Join(dir, "web")), false},
+		{"api sources", s.Match(filepath.Join(dir, "api"), []string{"src/**/*.go"}), true},
+		{"api docs", s.Match(filepath.Join(dir, "api"), []string{"docs/**"}), false},
+		{"web sources", s.Match(filepath.Join(dir, "web"), []string{"src/**"}), false},
+		{"shared sources outside the directory", s.Match(filepath.Join(dir, "web"), []string{"src/**", "../lib/*.md"}), true},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	s, err = For(dir, "main")
+	if err != nil {
+		t.Fatalf("For() with branch base error: %v", err)
+	}
+	if !s.Under(filepath.Join(dir, "api")) {
+		t.Error("expected changes since the merge base with main")
+	}
+
+	if _, err := For(dir, "nope"); err == nil || !strings.Contains(err.Error(), "comparing with nope") {
+		t.Errorf("expected unknown base error, got %v", err)
+	}
+}
+
+func TestForOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := For(t.TempDir(), ""); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
@@ -0,0 +1,245 @@
+package ci
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bab-sh/bab/internal/errs"
+)
+
+type Provider string
+
+const (
+	None   Provider = ""
+	GitHub Provider = "github"
+	GitLab Provider = "gitlab"
+)
+
+const (
+	modeAuto = "auto"
+	modeOff  = "off"
+)
+
+var ValidProviders = []Provider{GitHub, GitLab}
+
+func Detect() Provider {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return GitLab
+	default:
+		return None
+	}
+}
+
+func Resolve(mode string) (Provider, error) {
+	switch mode {
+	case "", modeAuto:
+		return Detect(), nil
+	case modeOff:
+		return None, nil
+	}
+	for _, p := range ValidProviders {
+		if Provider(mode) == p {
+			return p, nil
+		}
+	}
+	return None, fmt.Errorf("invalid CI mode %q: must be one of: github, gitlab, auto, off", mode)
+}
+
+func (p Provider) Enabled() bool {
+	return p != None
+}
+
+func (p Provider) Nestable() bool {
+	return p == GitLab
+}
+
+var sectionNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (p Provider) startGroup(w io.Writer, id, title string) {
+	switch p {
+	case GitHub:
+		_, _ = fmt.Fprintf(w, "::group::%s\n", escapeData(title))
+	case GitLab:
+		_, _ = fmt.Fprintf(w, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", time.Now().Unix(), id, title)
+	}
+}
+
+func (p Provider) endGroup(w io.Writer, id string) {
+	switch p {
+	case GitHub:
+		_, _ = fmt.Fprintln(w, "::endgroup::")
+	case GitLab:
+		_, _ = fmt.Fprintf(w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), id)
+	}
+}
+
+func (p Provider) Annotate(w io.Writer, err error) {
+	if !p.Enabled() || err == nil {
+		return
+	}
+
+	var verrs *errs.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, e := range verrs.Errors {
+			p.annotate(w, e)
+		}
+		return
+	}
+	p.annotate(w, err)
+}
+
+func (p Provider) annotate(w io.Writer, err error) {
+	path, line := errs.Locate(err)
+	if p == GitLab {
+		var loc string
+		msg := err.Error()
+		if path != "" {
+			loc = errs.FormatLocation(p.workspacePath(path), line, 0) + ": "
+			msg = strings.TrimPrefix(msg, errs.FormatLocation(path, line, 0)+": ")
+		}
+		msg = strings.ReplaceAll(msg, "\n", "\n  ")
+		_, _ = fmt.Fprintf(w, "\x1b[0K\x1b[31;1mERROR [bab]\x1b[0m %s%s\n", loc, msg)
+		return
+	}
+
+	var props []string
+	if path != "" {
+		props = append(props, "file="+escapeProperty(p.workspacePath(path)))
+		if line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", line))
+		}
+	}
+	props = append(props, "title=bab")
+	_, _ = fmt.Fprintf(w, "::error %s::%s\n", strings.Join(props, ","), escapeData(err.Error()))
+}
+
+func (p Provider) workspacePath(path string) string {
+	env := "GITHUB_WORKSPACE"
+	if p == GitLab {
+		env = "CI_PROJECT_DIR"
+	}
+	if ws := os.Getenv(env); ws != "" {
+		if rel, err := filepath.Rel(ws, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(errs.RelativePath(path))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(escapeData(s))
+}
+
+type group struct {
+	id, title string
+}
+
+type Groups struct {
+	provider Provider
+	w        io.Writer
+	mu       sync.Mutex
+	stack    []group
+	open     bool
+	seq      int
+}
+
+func NewGroups(p Provider, w io.Writer) *Groups {
+	if !p.Enabled() {
+		return nil
+	}
+	return &Groups{provider: p, w: w}
+}
+
+func (g *Groups) Push(title string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.push(title)
+}
+
+func (g *Groups) Pop() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pop()
+}
+
+// Resume reopens the enclosing group on providers that can't nest, where
+// Pop closes the child without reopening its parent until output follows.
+func (g *Groups) Resume() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resume()
+}
+
+// Section writes src as one complete group titled title.
+func (g *Groups) Section(title string, src io.WriterTo) {
+	if g == nil {
+		_, _ = src.WriteTo(os.Stdout)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.push(title)
+	_, _ = src.WriteTo(g.w)
+	g.pop()
+}
+
+func (g *Groups) push(title string) {
+	g.seq++
+	id := fmt.Sprintf("bab_%d_%s", g.seq, sectionNameRegex.ReplaceAllString(title, "_"))
+
+	if !g.provider.Nestable() && g.open {
+		g.provider.endGroup(g.w, g.stack[len(g.stack)-1].id)
+	}
+	g.provider.startGroup(g.w, id, title)
+	g.stack = append(g.stack, group{id: id, title: title})
+	g.open = true
+}
+
+func (g *Groups) pop() {
+	if len(g.stack) == 0 {
+		return
+	}
+	current := g.stack[len(g.stack)-1]
+	g.stack = g.stack[:len(g.stack)-1]
+
+	if g.provider.Nestable() {
+		g.provider.endGroup(g.w, current.id)
+		return
+	}
+	if g.open {
+		g.provider.endGroup(g.w, current.id)
+		g.open = false
+	}
+}
+
+func (g *Groups) resume() {
+	if g.provider.Nestable() || g.open || len(g.stack) == 0 {
+		return
+	}
+	parent := g.stack[len(g.stack)-1]
+	g.provider.startGroup(g.w, parent.id, parent.title+" (continued)")
+	g.open = true
+}
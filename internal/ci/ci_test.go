@@ -0,0 +1,200 @@
+package ci
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bab-sh/bab/internal/errs"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		github string
+		gitlab string
+		want   Provider
+	}{
+		{"none", "", "", None},
+		{"github", "true", "", GitHub},
+		{"gitlab", "", "true", GitLab},
+		{"github wins", "true", "true", GitHub},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tt.github)
+			t.Setenv("GITLAB_CI", tt.gitlab)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITLAB_CI", "")
+
+	tests := []struct {
+		mode    string
+		want    Provider
+		wantErr bool
+	}{
+		{"", GitHub, false},
+		{"auto", GitHub, false},
+		{"off", None, false},
+		{"github", GitHub, false},
+		{"gitlab", GitLab, false},
+		{"jenkins", None, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := Resolve(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupsGitHubDoesNotNest(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGroups(GitHub, &buf)
+
+	g.Push("build")
+	g.Push("deps")
+	g.Pop()
+	g.Push("lint")
+	g.Pop()
+	g.Resume()
+	g.Pop()
+
+	want := "::group::build\n::endgroup::\n::group::deps\n::endgroup::\n::group::lint\n::endgroup::\n::group::build (continued)\n::endgroup::\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGroupsGitHubSkipsEmptyContinuation(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGroups(GitHub, &buf)
+
+	g.Push("build")
+	g.Push("deps")
+	g.Pop()
+	g.Pop()
+
+	want := "::group::build\n::endgroup::\n::group::deps\n::endgroup::\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGroupsSection(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGroups(GitHub, &buf)
+
+	g.Push("ci")
+	g.Section("test", bytes.NewBufferString("ok\n"))
+	g.Pop()
+
+	want := "::group::ci\n::endgroup::\n::group::test\nok\n::endgroup::\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGroupsGitLabNests(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGroups(GitLab, &buf)
+
+	g.Push("ci:build")
+	g.Push("lint")
+	g.Pop()
+	g.Pop()
+
+	out := buf.String()
+	if strings.Count(out, "section_start:") != 2 || strings.Count(out, "section_end:") != 2 {
+		t.Fatalf("expected two nested sections, got %q", out)
+	}
+	if !strings.Contains(out, ":bab_1_ci_build[collapsed=true]") {
+		t.Errorf("section name should be sanitized, got %q", out)
+	}
+	if strings.Index(out, "section_end:") < strings.LastIndex(out, "section_start:") {
+		t.Errorf("inner section should end before outer section, got %q", out)
+	}
+}
+
+func TestGroupsNilSafe(t *testing.T) {
+	g := NewGroups(None, os.Stdout)
+	if g != nil {
+		t.Fatal("NewGroups(None) should return nil")
+	}
+	g.Push("task")
+	g.Resume()
+	g.Pop()
+}
+
+func TestAnnotate(t *testing.T) {
+	cwd, _ := os.Getwd()
+	t.Setenv("GITHUB_WORKSPACE", cwd)
+	path := filepath.Join(cwd, "Babfile.yml")
+
+	t.Run("validation errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		verrs := &errs.ValidationErrors{}
+		verrs.Add(&errs.TaskNotFoundError{Path: path, Line: 4, TaskName: "buld"})
+		verrs.Add(&errs.ParseError{Path: path, Line: 9, Message: "env must be a mapping"})
+
+		GitHub.Annotate(&buf, verrs)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 annotations, got %q", buf.String())
+		}
+		if !strings.HasPrefix(lines[0], "::error file=Babfile.yml,line=4,title=bab::") {
+			t.Errorf("unexpected annotation: %q", lines[0])
+		}
+		if !strings.HasPrefix(lines[1], "::error file=Babfile.yml,line=9,title=bab::") {
+			t.Errorf("unexpected annotation: %q", lines[1])
+		}
+	})
+
+	t.Run("wrapped command error", func(t *testing.T) {
+		var buf bytes.Buffer
+		cmdErr := &errs.CommandError{Path: path, Line: 12, Task: "test", Cmd: "go test", Err: errors.New("exit status 1")}
+
+		GitHub.Annotate(&buf, fmt.Errorf("dependency %q failed: %w", "test", cmdErr))
+
+		out := buf.String()
+		if !strings.HasPrefix(out, "::error file=Babfile.yml,line=12,title=bab::dependency") {
+			t.Errorf("unexpected annotation: %q", out)
+		}
+	})
+
+	t.Run("multi-line message is escaped", func(t *testing.T) {
+		var buf bytes.Buffer
+		GitHub.Annotate(&buf, errors.New("first\nsecond 100%"))
+		if got := buf.String(); got != "::error title=bab::first%0Asecond 100%25\n" {
+			t.Errorf("unexpected annotation: %q", got)
+		}
+	})
+
+	t.Run("gitlab error line", func(t *testing.T) {
+		t.Setenv("CI_PROJECT_DIR", cwd)
+		var buf bytes.Buffer
+		GitLab.Annotate(&buf, &errs.ParseError{Path: path, Line: 9, Message: "env must be a mapping"})
+		if got := buf.String(); got != "\x1b[0K\x1b[31;1mERROR [bab]\x1b[0m Babfile.yml:9: env must be a mapping\n" {
+			t.Errorf("unexpected error line: %q", got)
+		}
+	})
+}
@@ -1,18 +1,14 @@
 package condition
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
+	"github.com/bab-sh/bab/internal/errs"
 	"github.com/bab-sh/bab/internal/interpolate"
 )
 
-var (
-	comparisonPatternSQ = regexp.MustCompile(`^(.+?)\s*(==|!=)\s*'([^']*)'$`)
-	comparisonPatternDQ = regexp.MustCompile(`^(.+?)\s*(==|!=)\s*"([^"]*)"$`)
-)
-
 type Result struct {
 	ShouldRun bool
 	Reason    string
@@ -22,56 +18,44 @@ func Evaluate(condition string, ctx *interpolate.Context) (Result, error) {
 	if condition == "" {
 		return Result{ShouldRun: true, Reason: "no condition"}, nil
 	}
-
-	interpolated, err := interpolate.Interpolate(condition, ctx)
-	if err != nil {
-		return Result{ShouldRun: false}, fmt.Errorf("evaluating condition: %w", err)
+	if ctx == nil {
+		ctx = interpolate.NewContext(nil)
 	}
 
-	interpolated = strings.TrimSpace(interpolated)
-
-	if result, ok := evaluateComparison(interpolated); ok {
-		return result, nil
+	expr, err := Parse(condition)
+	if err != nil {
+		return Result{ShouldRun: false}, locate(err, ctx)
 	}
-
-	return evaluateTruthy(interpolated), nil
+	return expr.Eval(ctx)
 }
 
-func evaluateComparison(s string) (Result, bool) {
-	if matches := comparisonPatternSQ.FindStringSubmatch(s); len(matches) == 4 {
-		left := strings.TrimSpace(matches[1])
-		op := matches[2]
-		right := matches[3]
-		left = strings.Trim(left, "'\"")
-		return evaluateOp(left, op, right), true
+func (e *Expr) Eval(ctx *interpolate.Context) (Result, error) {
+	if ctx == nil {
+		ctx = interpolate.NewContext(nil)
 	}
 
-	if matches := comparisonPatternDQ.FindStringSubmatch(s); len(matches) == 4 {
-		left := strings.TrimSpace(matches[1])
-		op := matches[2]
-		right := matches[3]
-		left = strings.Trim(left, "'\"")
-		return evaluateOp(left, op, right), true
+	ev := &evaluator{src: e.src, ctx: ctx}
+	value, err := ev.eval(e.root)
+	if err != nil {
+		return Result{ShouldRun: false}, fmt.Errorf("evaluating condition: %w", locate(err, ctx))
 	}
 
-	return Result{}, false
+	switch e.root.(type) {
+	case *binaryNode, *notNode:
+		ok := truthy(value)
+		return Result{ShouldRun: ok, Reason: fmt.Sprintf("%s is %v", strings.TrimSpace(e.src), ok)}, nil
+	default:
+		return evaluateTruthy(value), nil
+	}
 }
 
-func evaluateOp(left, op, right string) Result {
-	switch op {
-	case "==":
-		return Result{
-			ShouldRun: left == right,
-			Reason:    fmt.Sprintf("%q == %q is %v", left, right, left == right),
-		}
-	case "!=":
-		return Result{
-			ShouldRun: left != right,
-			Reason:    fmt.Sprintf("%q != %q is %v", left, right, left != right),
-		}
-	default:
-		return Result{ShouldRun: false, Reason: fmt.Sprintf("unknown operator: %s", op)}
+func locate(err error, ctx *interpolate.Context) error {
+	var condErr *errs.ConditionError
+	if errors.As(err, &condErr) && condErr.Path == "" {
+		condErr.Path = ctx.Path
+		condErr.Line = ctx.Line
 	}
+	return err
 }
 
 func evaluateTruthy(s string) Result {
@@ -1,8 +1,14 @@
 package condition
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/bab-sh/bab/internal/errs"
 	"github.com/bab-sh/bab/internal/interpolate"
 )
 
@@ -20,7 +26,7 @@ func TestEvaluate_EmptyCondition(t *testing.T) {
 }
 
 func TestEvaluate_Equality(t *testing.T) {
-	ctx := interpolate.NewContext(map[string]string{"env": "prod", "name": "test"})
+	ctx := interpolate.NewContext(map[string]any{"env": "prod", "name": "test"})
 
 	tests := []struct {
 		name      string
@@ -51,7 +57,7 @@ func TestEvaluate_Equality(t *testing.T) {
 }
 
 func TestEvaluate_Truthy(t *testing.T) {
-	ctx := interpolate.NewContext(map[string]string{
+	ctx := interpolate.NewContext(map[string]any{
 		"hasValue": "yes",
 		"isEmpty":  "",
 		"isFalse":  "false",
@@ -114,7 +120,7 @@ func TestEvaluate_ConfirmPromptPattern(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := interpolate.NewContext(map[string]string{"confirm": tt.confirm})
+			ctx := interpolate.NewContext(map[string]any{"confirm": tt.confirm})
 			result, err := Evaluate(tt.condition, ctx)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -127,7 +133,7 @@ func TestEvaluate_ConfirmPromptPattern(t *testing.T) {
 }
 
 func TestEvaluate_SelectPromptPattern(t *testing.T) {
-	ctx := interpolate.NewContext(map[string]string{"environment": "staging"})
+	ctx := interpolate.NewContext(map[string]any{"environment": "staging"})
 
 	tests := []struct {
 		name      string
@@ -186,26 +192,186 @@ func TestEvaluateComparison(t *testing.T) {
 	tests := []struct {
 		input     string
 		shouldRun bool
-		found     bool
 	}{
-		{"prod == 'prod'", true, true},
-		{"prod == 'dev'", false, true},
-		{"prod != 'dev'", true, true},
-		{"prod != 'prod'", false, true},
-		{"'prod' == 'prod'", true, true},
-		{"\"prod\" == \"prod\"", true, true},
-		{"just a value", false, false},
+		{"prod == 'prod'", true},
+		{"prod == 'dev'", false},
+		{"prod != 'dev'", true},
+		{"prod != 'prod'", false},
+		{"'prod' == 'prod'", true},
+		{"\"prod\" == \"prod\"", true},
+		{"just_a_value", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			result, found := evaluateComparison(tt.input)
-			if found != tt.found {
-				t.Errorf("evaluateComparison(%q): expected found=%v, got %v", tt.input, tt.found, found)
+			result, err := Evaluate(tt.input, nil)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): unexpected error: %v", tt.input, err)
+			}
+			if result.ShouldRun != tt.shouldRun {
+				t.Errorf("Evaluate(%q): expected shouldRun=%v, got %v", tt.input, tt.shouldRun, result.ShouldRun)
+			}
+		})
+	}
+}
+
+func TestEvaluate_Expressions(t *testing.T) {
+	t.Setenv("BAB_CONDITION_TEST", "yes")
+	ctx := interpolate.NewContext(map[string]any{
+		"env":     "prod",
+		"ci":      "true",
+		"skip":    "false",
+		"count":   "12",
+		"version": "1.10.0",
+		"targets": "api, web",
+		"msg":     "a == 'b'",
+	})
+
+	tests := []struct {
+		name      string
+		condition string
+		shouldRun bool
+	}{
+		{"and", "${{ env }} == 'prod' && ${{ ci }}", true},
+		{"and short", "${{ env }} == 'dev' && ${{ ci }}", false},
+		{"or", "${{ env }} == 'dev' || ${{ ci }}", true},
+		{"not", "!${{ skip }}", true},
+		{"not comparison", "!(${{ env }} == 'prod')", false},
+		{"precedence", "${{ skip }} && ${{ ci }} || ${{ env }} == 'prod'", true},
+		{"parentheses", "${{ skip }} && (${{ ci }} || ${{ env }} == 'prod')", false},
+		{"in list", "${{ env }} in ['staging', 'prod']", true},
+		{"not in list", "${{ env }} in [dev, staging]", false},
+		{"in comma list", "'web' in ${{ targets }}", true},
+		{"regex", "${{ env }} =~ '^pr'", true},
+		{"regex no match", "${{ env }} =~ '^dev'", false},
+		{"numeric", "${{ count }} > 9", true},
+		{"numeric equal", "${{ count }} >= 12", true},
+		{"semver", "${{ version }} > 1.9.2", true},
+		{"semver less", "${{ version }} < v1.2", false},
+		{"version minor above nine", "'1.10' > '1.9'", true},
+		{"version with numbers", "'1.10' >= 1", true},
+		{"os", "os == '" + runtime.GOOS + "'", true},
+		{"arch", "arch != '" + runtime.GOARCH + "'", false},
+		{"env function", "env('BAB_CONDITION_TEST') == 'yes'", true},
+		{"env function unset", "env('BAB_CONDITION_UNSET')", false},
+		{"has_cmd", "has_cmd('sh')", runtime.GOOS != "windows"},
+		{"has_cmd missing", "has_cmd('bab-missing-command')", false},
+		{"exists", "exists('condition.go')", true},
+		{"exists missing", "!exists('missing.txt')", true},
+		{"value with operators", "${{ msg }}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Evaluate(tt.condition, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ShouldRun != tt.shouldRun {
+				t.Errorf("expected ShouldRun=%v, got %v (reason: %s)", tt.shouldRun, result.ShouldRun, result.Reason)
+			}
+		})
+	}
+
+	ctx.Platform = "windows/arm64"
+	if result, err := Evaluate("os == 'windows' && arch == 'arm64'", ctx); err != nil || !result.ShouldRun {
+		t.Errorf("expected the target platform to be used, got %+v, %v", result, err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		condition string
+		pos       int
+	}{
+		{"os == 'linux' &&", 16},
+		{"os = 'linux'", 3},
+		{"(os == 'linux'", 14},
+		{"os == 'linux", 6},
+		{"missing('x')", 0},
+		{"exists()", 0},
+		{"os == 'linux')", 13},
+		{"${{ env == 'x'", 0},
+		{"env =~ '('", 7},
+		{"os in [linux", 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := Parse(tt.condition)
+			var condErr *errs.ConditionError
+			if !errors.As(err, &condErr) {
+				t.Fatalf("expected ConditionError, got %v", err)
+			}
+			if condErr.Pos != tt.pos {
+				t.Errorf("expected position %d, got %d (%v)", tt.pos, condErr.Pos, err)
+			}
+		})
+	}
+}
+
+func TestEvaluate_RuntimeErrors(t *testing.T) {
+	ctx := interpolate.NewContextWithLocation(map[string]any{"name": "api", "pattern": "("}, "Babfile.yml", 7)
+
+	for _, condition := range []string{
+		"${{ name }} > 2",
+		"${{ name }} =~ ${{ pattern }}",
+	} {
+		t.Run(condition, func(t *testing.T) {
+			_, err := Evaluate(condition, ctx)
+			if !errors.Is(err, errs.ErrInvalidCondition) {
+				t.Fatalf("expected invalid condition error, got %v", err)
 			}
-			if found && result.ShouldRun != tt.shouldRun {
-				t.Errorf("evaluateComparison(%q): expected shouldRun=%v, got %v", tt.input, tt.shouldRun, result.ShouldRun)
+			if path, line := errs.Locate(err); path != "Babfile.yml" || line != 7 {
+				t.Errorf("expected location Babfile.yml:7, got %s:%d", path, line)
 			}
 		})
 	}
 }
+
+func TestEvaluate_Changed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=bab", "-c", "user.email=bab@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := interpolate.NewContextWithLocation(nil, filepath.Join(dir, "Babfile.yml"), 3)
+	ctx.Base = "HEAD"
+
+	for condition, want := range map[string]bool{
+		"changed('src/**')":          true,
+		"changed('src/*.go')":        true,
+		"changed('docs/**')":         false,
+		"!changed('Babfile.yml')":    true,
+		"changed('**/main.go') && 1": true,
+	} {
+		result, err := Evaluate(condition, ctx)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", condition, err)
+		}
+		if result.ShouldRun != want {
+			t.Errorf("%s: expected ShouldRun=%v, got %v", condition, want, result.ShouldRun)
+		}
+	}
+
+	outside := interpolate.NewContextWithLocation(nil, filepath.Join(t.TempDir(), "Babfile.yml"), 3)
+	if _, err := Evaluate("changed('src/**')", outside); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
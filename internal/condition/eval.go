@@ -0,0 +1,230 @@
+package condition
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/changes"
+	"github.com/bab-sh/bab/internal/interpolate"
+	goversion "github.com/hashicorp/go-version"
+)
+
+type function struct {
+	arity int
+	call  func(ev *evaluator, args []string) (string, error)
+}
+
+var functions = map[string]function{
+	"exists": {arity: 1, call: func(ev *evaluator, args []string) (string, error) {
+		_, err := os.Stat(ev.resolvePath(args[0]))
+		return strconv.FormatBool(err == nil), nil
+	}},
+	"env": {arity: 1, call: func(_ *evaluator, args []string) (string, error) {
+		return os.Getenv(args[0]), nil
+	}},
+	"has_cmd": {arity: 1, call: func(_ *evaluator, args []string) (string, error) {
+		_, err := exec.LookPath(args[0])
+		return strconv.FormatBool(err == nil), nil
+	}},
+	"changed": {arity: 1, call: func(ev *evaluator, args []string) (string, error) {
+		dir := ev.resolvePath(".")
+		set, err := changes.For(dir, ev.ctx.Base)
+		if err != nil {
+			return "", fmt.Errorf("changed(%q): %w", args[0], err)
+		}
+		return strconv.FormatBool(set.Match(dir, []string{args[0]})), nil
+	}},
+}
+
+type evaluator struct {
+	src string
+	ctx *interpolate.Context
+}
+
+func (ev *evaluator) resolvePath(path string) string {
+	if filepath.IsAbs(path) || ev.ctx.Path == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(ev.ctx.Path), path)
+}
+
+func (ev *evaluator) eval(n node) (string, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return interpolate.Interpolate(n.text, ev.ctx)
+	case *identNode:
+		goos, goarch := ev.ctx.OSArch()
+		switch n.name {
+		case "os":
+			return goos, nil
+		case "arch":
+			return goarch, nil
+		case "profile":
+			return ev.ctx.Profile, nil
+		default:
+			return "", newError(ev.src, n.pos, "unknown identifier %q", n.name)
+		}
+	case *callNode:
+		args := make([]string, len(n.args))
+		for i, arg := range n.args {
+			v, err := ev.eval(arg)
+			if err != nil {
+				return "", err
+			}
+			args[i] = v
+		}
+		return functions[n.name].call(ev, args)
+	case *notNode:
+		v, err := ev.eval(n.x)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(!truthy(v)), nil
+	case *binaryNode:
+		ok, err := ev.evalBinary(n)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(ok), nil
+	default:
+		return "", newError(ev.src, n.position(), "list is only allowed after \"in\"")
+	}
+}
+
+func (ev *evaluator) evalBinary(n *binaryNode) (bool, error) {
+	switch n.op {
+	case tokAnd, tokOr:
+		return ev.evalLogical(n)
+	case tokIn:
+		return ev.evalIn(n)
+	}
+
+	left, err := ev.eval(n.left)
+	if err != nil {
+		return false, err
+	}
+	right, err := ev.eval(n.right)
+	if err != nil {
+		return false, err
+	}
+
+	switch n.op {
+	case tokEq:
+		return left == right, nil
+	case tokNe:
+		return left != right, nil
+	case tokMatch:
+		re, err := regexp.Compile(right)
+		if err != nil {
+			return false, newError(ev.src, n.right.position(), "invalid regular expression %q", right)
+		}
+		return re.MatchString(left), nil
+	default:
+		cmp, ok := compare(left, right)
+		if !ok {
+			return false, newError(ev.src, n.pos, "cannot compare %q and %q: values must be numbers or versions", left, right)
+		}
+		switch n.op {
+		case tokLt:
+			return cmp < 0, nil
+		case tokLe:
+			return cmp <= 0, nil
+		case tokGt:
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	}
+}
+
+func (ev *evaluator) evalLogical(n *binaryNode) (bool, error) {
+	left, err := ev.eval(n.left)
+	if err != nil {
+		return false, err
+	}
+	if n.op == tokAnd && !truthy(left) {
+		return false, nil
+	}
+	if n.op == tokOr && truthy(left) {
+		return true, nil
+	}
+	right, err := ev.eval(n.right)
+	if err != nil {
+		return false, err
+	}
+	return truthy(right), nil
+}
+
+func (ev *evaluator) evalIn(n *binaryNode) (bool, error) {
+	left, err := ev.eval(n.left)
+	if err != nil {
+		return false, err
+	}
+
+	var items []string
+	if list, ok := n.right.(*listNode); ok {
+		for _, item := range list.items {
+			v, err := ev.eval(item)
+			if err != nil {
+				return false, err
+			}
+			items = append(items, v)
+		}
+	} else {
+		v, err := ev.eval(n.right)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range strings.Split(v, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+
+	for _, item := range items {
+		if item == left {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func compare(left, right string) (int, bool) {
+	if !strings.Contains(left, ".") && !strings.Contains(right, ".") {
+		if c, ok := compareNumbers(left, right); ok {
+			return c, true
+		}
+	}
+
+	lv, lerr := goversion.NewVersion(left)
+	rv, rerr := goversion.NewVersion(right)
+	if lerr == nil && rerr == nil {
+		return lv.Compare(rv), true
+	}
+	return compareNumbers(left, right)
+}
+
+func compareNumbers(left, right string) (int, bool) {
+	l, lerr := strconv.ParseFloat(left, 64)
+	r, rerr := strconv.ParseFloat(right, 64)
+	if lerr != nil || rerr != nil {
+		return 0, false
+	}
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func truthy(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && !strings.EqualFold(s, "false")
+}
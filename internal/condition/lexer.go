@@ -0,0 +1,165 @@
+package condition
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokNot
+	tokAnd
+	tokOr
+	tokEq
+	tokNe
+	tokLt
+	tokLe
+	tokGt
+	tokGe
+	tokMatch
+	tokIn
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:      "end of condition",
+	tokLParen:   `"("`,
+	tokRParen:   `")"`,
+	tokLBracket: `"["`,
+	tokRBracket: `"]"`,
+	tokComma:    `","`,
+	tokNot:      `"!"`,
+	tokAnd:      `"&&"`,
+	tokOr:       `"||"`,
+	tokEq:       `"=="`,
+	tokNe:       `"!="`,
+	tokLt:       `"<"`,
+	tokLe:       `"<="`,
+	tokGt:       `">"`,
+	tokGe:       `">="`,
+	tokMatch:    `"=~"`,
+	tokIn:       `"in"`,
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokWord:
+		return fmt.Sprintf("%q", t.text)
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return tokenNames[t.kind]
+	}
+}
+
+const wordTerminators = " \t\r\n()[],!=<>&|'\""
+
+var operators = []struct {
+	text string
+	kind tokenKind
+}{
+	{"&&", tokAnd},
+	{"||", tokOr},
+	{"==", tokEq},
+	{"!=", tokNe},
+	{"=~", tokMatch},
+	{"<=", tokLe},
+	{">=", tokGe},
+	{"<", tokLt},
+	{">", tokGt},
+	{"!", tokNot},
+	{"(", tokLParen},
+	{")", tokRParen},
+	{"[", tokLBracket},
+	{"]", tokRBracket},
+	{",", tokComma},
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, newError(src, i, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case strings.IndexByte(wordTerminators, c) >= 0:
+			tok, ok := lexOperator(src, i)
+			if !ok {
+				return nil, newError(src, i, "unexpected %q", string(c))
+			}
+			tokens = append(tokens, tok)
+			i += len(tok.text)
+		default:
+			tok, err := lexWord(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i += len(tok.text)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func lexOperator(src string, pos int) (token, bool) {
+	for _, op := range operators {
+		if strings.HasPrefix(src[pos:], op.text) {
+			return token{kind: op.kind, text: op.text, pos: pos}, true
+		}
+	}
+	return token{}, false
+}
+
+func lexWord(src string, pos int) (token, error) {
+	i := pos
+	for i < len(src) && strings.IndexByte(wordTerminators, src[i]) < 0 {
+		if ref := varRefStart(src[i:]); ref > 0 {
+			end := strings.Index(src[i+ref:], "}}")
+			if end < 0 {
+				return token{}, newError(src, i, "unterminated variable reference")
+			}
+			i += ref + end + 2
+			continue
+		}
+		i++
+	}
+	text := src[pos:i]
+	kind := tokWord
+	if text == "in" {
+		kind = tokIn
+	}
+	return token{kind: kind, text: text, pos: pos}, nil
+}
+
+func varRefStart(s string) int {
+	switch {
+	case strings.HasPrefix(s, "$${{"):
+		return 4
+	case strings.HasPrefix(s, "${{"):
+		return 3
+	default:
+		return 0
+	}
+}
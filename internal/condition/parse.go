@@ -0,0 +1,281 @@
+package condition
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/interpolate"
+)
+
+type node interface {
+	position() int
+}
+
+type literalNode struct {
+	pos  int
+	text string
+}
+
+type identNode struct {
+	pos  int
+	name string
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []node
+}
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+type notNode struct {
+	pos int
+	x   node
+}
+
+type binaryNode struct {
+	pos         int
+	op          tokenKind
+	left, right node
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *identNode) position() int   { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+func (n *notNode) position() int     { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+
+var identifiers = map[string]bool{
+	"os":      true,
+	"arch":    true,
+	"profile": true,
+}
+
+type Expr struct {
+	src  string
+	root node
+}
+
+func Parse(condition string) (*Expr, error) {
+	tokens, err := lex(condition)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: condition, tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, newError(condition, 0, "empty condition")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, newError(condition, tok.pos, "unexpected %s", tok)
+	}
+	return &Expr{src: condition, root: root}, nil
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, newError(p.src, tok.pos, "expected %s, got %s", tokenNames[kind], tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: tokOr, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		op := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: tokAnd, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		op := p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{pos: op.pos, x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch op.kind {
+	case tokEq, tokNe, tokLt, tokLe, tokGt, tokGe:
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{pos: op.pos, op: op.kind, left: left, right: right}, nil
+	case tokMatch:
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := right.(*literalNode); ok && !interpolate.ContainsVarRef(lit.text) {
+			if _, err := regexp.Compile(lit.text); err != nil {
+				return nil, newError(p.src, lit.pos, "invalid regular expression %q", lit.text)
+			}
+		}
+		return &binaryNode{pos: op.pos, op: tokMatch, left: left, right: right}, nil
+	case tokIn:
+		p.next()
+		var right node
+		if p.peek().kind == tokLBracket {
+			right, err = p.parseList()
+		} else {
+			right, err = p.parseOperand()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{pos: op.pos, op: tokIn, left: left, right: right}, nil
+	default:
+		return left, nil
+	}
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case tokString:
+		return &literalNode{pos: tok.pos, text: tok.text}, nil
+	case tokWord:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		if identifiers[tok.text] {
+			return &identNode{pos: tok.pos, name: tok.text}, nil
+		}
+		return &literalNode{pos: tok.pos, text: tok.text}, nil
+	case tokEOF:
+		return nil, newError(p.src, tok.pos, "unexpected end of condition")
+	default:
+		return nil, newError(p.src, tok.pos, "unexpected %s", tok)
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, newError(p.src, name.pos, "unknown function %q", name.text)
+	}
+	p.next()
+
+	call := &callNode{pos: name.pos, name: name.text}
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	if len(call.args) != fn.arity {
+		return nil, newError(p.src, name.pos, "%s() takes %d argument(s), got %d", name.text, fn.arity, len(call.args))
+	}
+	return call, nil
+}
+
+func (p *parser) parseList() (node, error) {
+	open := p.next()
+	list := &listNode{pos: open.pos}
+	if p.peek().kind != tokRBracket {
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(tokRBracket); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func newError(src string, pos int, format string, args ...any) *errs.ConditionError {
+	return &errs.ConditionError{Condition: src, Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,72 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `# deployment settings
+REGION=eu-west-1
+export STAGE=staging
+EMPTY=
+URL=https://example.com/#anchor # comment
+QUOTED="hello world" # comment
+ESCAPED="line1\nline2"
+SINGLE='keep ${{ this }} \n'
+`
+	values, err := Parse(".env", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := map[string]string{
+		"REGION":  "eu-west-1",
+		"STAGE":   "staging",
+		"EMPTY":   "",
+		"URL":     "https://example.com/#anchor",
+		"QUOTED":  "hello world",
+		"ESCAPED": "line1\nline2",
+		"SINGLE":  `keep ${{ this }} \n`,
+	}
+	if len(values) != len(want) {
+		t.Errorf("expected %d values, got %v", len(want), values)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("%s = %q, want %q", k, values[k], v)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"REGION eu-west-1\n", `.env:1: expected KEY=value, got "REGION eu-west-1"`},
+		{"OK=1\n1BAD=x\n", `.env:2: expected KEY=value`},
+		{"NAME=\"open\n", `.env:1: NAME: unterminated " quote`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(".env", []byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) expected error containing %q, got %v", tt.data, tt.want, err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := Read(path)
+	if err != nil || values["A"] != "1" {
+		t.Errorf("Read() = %v, %v", values, err)
+	}
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
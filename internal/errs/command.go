@@ -0,0 +1,19 @@
+package errs
+
+import "fmt"
+
+type CommandError struct {
+	Path string
+	Line int
+	Task string
+	Cmd  string
+	Err  error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("task %q: command %q failed: %v", e.Task, e.Cmd, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,23 @@
+package errs
+
+import "fmt"
+
+type ConditionError struct {
+	Path      string
+	Line      int
+	Condition string
+	Pos       int
+	Message   string
+}
+
+func (e *ConditionError) Error() string {
+	msg := fmt.Sprintf("invalid condition %q: %s at position %d", e.Condition, e.Message, e.Pos+1)
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s", FormatLocation(e.Path, e.Line, 0), msg)
+	}
+	return msg
+}
+
+func (e *ConditionError) Is(target error) bool {
+	return target == ErrInvalidCondition
+}
@@ -16,6 +16,8 @@ var (
 	ErrVarNotFound = errors.New("variable not found")
 	ErrVarCycle    = errors.New("circular variable reference")
 
+	ErrInvalidCondition = errors.New("invalid condition")
+
 	ErrBabfileNotFound = errors.New("no Babfile found")
 
 	ErrNoTasks = errors.New("no tasks available")
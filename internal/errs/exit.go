@@ -0,0 +1,64 @@
+package errs
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitSignalBase  = 128
+	ExitInterrupted = ExitSignalBase + int(syscall.SIGINT)
+)
+
+var ErrUsage = errors.New("usage error")
+
+type SignalError struct {
+	Signal syscall.Signal
+}
+
+func (e *SignalError) Error() string {
+	return "received " + e.Signal.String()
+}
+
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UsageError) Is(target error) bool {
+	return target == ErrUsage
+}
+
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, ErrUsage) {
+		return ExitUsage
+	}
+	var sigErr *SignalError
+	if errors.As(err, &sigErr) {
+		return ExitSignalBase + int(sigErr.Signal)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return ExitSignalBase + int(ws.Signal())
+		}
+		if code := exitErr.ExitCode(); code > 0 {
+			return code
+		}
+	}
+	return ExitFailure
+}
@@ -76,3 +76,22 @@ func (e *VarCycleError) location() string {
 func (e *VarCycleError) Is(target error) bool {
 	return target == ErrVarCycle
 }
+
+type ExprError struct {
+	Path string
+	Line int
+	Expr string
+	Err  error
+}
+
+func (e *ExprError) Error() string {
+	msg := fmt.Sprintf("invalid expression %q: %v", e.Expr, e.Err)
+	if e.Path == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", FormatLocation(e.Path, e.Line, 0), msg)
+}
+
+func (e *ExprError) Unwrap() error {
+	return e.Err
+}
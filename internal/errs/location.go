@@ -1,6 +1,7 @@
 package errs
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -26,3 +27,45 @@ func FormatLocation(path string, line, column int) string {
 	}
 	return path
 }
+
+func Locate(err error) (string, int) {
+	var (
+		parseErr    *ParseError
+		cmdErr      *CommandError
+		notFoundErr *TaskNotFoundError
+		dupTaskErr  *DuplicateTaskError
+		conflictErr *AliasConflictError
+		dupAliasErr *DuplicateAliasError
+		varErr      *VarNotFoundError
+		cycleErr    *VarCycleError
+		condErr     *ConditionError
+		exprErr     *ExprError
+		circularErr *CircularDepError
+	)
+	switch {
+	case errors.As(err, &cmdErr):
+		return cmdErr.Path, cmdErr.Line
+	case errors.As(err, &parseErr):
+		return parseErr.Path, parseErr.Line
+	case errors.As(err, &notFoundErr):
+		return notFoundErr.Path, notFoundErr.Line
+	case errors.As(err, &dupTaskErr):
+		return dupTaskErr.Path, dupTaskErr.Line
+	case errors.As(err, &conflictErr):
+		return conflictErr.Path, conflictErr.Line
+	case errors.As(err, &dupAliasErr):
+		return dupAliasErr.Path, dupAliasErr.Line
+	case errors.As(err, &varErr):
+		return varErr.Path, varErr.Line
+	case errors.As(err, &cycleErr):
+		return cycleErr.Path, cycleErr.Line
+	case errors.As(err, &exprErr):
+		return exprErr.Path, exprErr.Line
+	case errors.As(err, &condErr):
+		return condErr.Path, condErr.Line
+	case errors.As(err, &circularErr):
+		return circularErr.Path, circularErr.Line
+	default:
+		return "", 0
+	}
+}
@@ -40,6 +40,7 @@ func (e *ParseError) Is(target error) bool {
 
 type CircularDepError struct {
 	Path  string
+	Line  int
 	Type  string
 	Chain []string
 }
@@ -47,7 +48,7 @@ type CircularDepError struct {
 func (e *CircularDepError) Error() string {
 	chainStr := strings.Join(e.Chain, " → ")
 	if e.Path != "" {
-		return fmt.Sprintf("%s: circular %s: %s", RelativePath(e.Path), e.Type, chainStr)
+		return fmt.Sprintf("%s: circular %s: %s", FormatLocation(e.Path, e.Line, 0), e.Type, chainStr)
 	}
 	return fmt.Sprintf("circular %s: %s", e.Type, chainStr)
 }
@@ -62,6 +63,7 @@ type TaskNotFoundError struct {
 	TaskName     string
 	ReferencedBy string
 	Available    []string
+	Unfetched    []string
 }
 
 func (e *TaskNotFoundError) Error() string {
@@ -76,7 +78,9 @@ func (e *TaskNotFoundError) Error() string {
 	}
 
 	var suffix string
-	if suggestion := FindSimilar(e.TaskName, e.Available); suggestion != "" {
+	if len(e.Unfetched) > 0 {
+		suffix = fmt.Sprintf(" (remote include %s isn't fetched yet, run \"bab includes update\")", strings.Join(e.Unfetched, ", "))
+	} else if suggestion := FindSimilar(e.TaskName, e.Available); suggestion != "" {
 		suffix = fmt.Sprintf(" (did you mean %q?)", suggestion)
 	}
 
@@ -91,11 +95,15 @@ type DuplicateTaskError struct {
 	Path         string
 	Line         int
 	TaskName     string
+	OriginalPath string
 	OriginalLine int
 }
 
 func (e *DuplicateTaskError) Error() string {
 	path := RelativePath(e.Path)
+	if e.OriginalPath != "" && e.OriginalPath != e.Path {
+		return fmt.Sprintf("%s:%d: duplicate task %q (first defined at %s:%d)", path, e.Line, e.TaskName, RelativePath(e.OriginalPath), e.OriginalLine)
+	}
 	return fmt.Sprintf("%s:%d: duplicate task %q (first defined at line %d)", path, e.Line, e.TaskName, e.OriginalLine)
 }
 
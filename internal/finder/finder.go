@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/paths"
 	"github.com/charmbracelet/log"
 )
 
@@ -43,3 +44,70 @@ func FindBabfile() (string, error) {
 	log.Debug("Babfile not found", "searched", cwd)
 	return "", fmt.Errorf("%w in current directory or any parent directories", errs.ErrBabfileNotFound)
 }
+
+func FindBabfiles(root string, maxDepth int) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+
+	var found []string
+	if err := findBabfiles(root, 0, maxDepth, nil, &found); err != nil {
+		return nil, err
+	}
+	log.Debug("Found Babfiles", "root", root, "count", len(found))
+	return found, nil
+}
+
+func findBabfiles(dir string, depth, maxDepth int, rules []ignoreRule, found *[]string) error {
+	local, err := loadIgnoreRules(dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", filepath.Join(dir, gitignoreName), err)
+	}
+	rules = append(rules[:len(rules):len(rules)], local...)
+
+	for _, filename := range babfileNames {
+		path := filepath.Join(dir, filename)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			if !isIgnored(rules, path, false) {
+				*found = append(*found, path)
+			}
+			break
+		}
+	}
+
+	if maxDepth >= 0 && depth >= maxDepth {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+		sub := filepath.Join(dir, entry.Name())
+		if isIgnored(rules, sub, true) {
+			log.Debug("Skipping ignored directory", "path", sub)
+			continue
+		}
+		if err := findBabfiles(sub, depth+1, maxDepth, rules, found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func FindGlobalBabfile() (string, error) {
+	dir := paths.ConfigDir()
+	for _, filename := range babfileNames {
+		path := filepath.Join(dir, filename)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			log.Debug("Found global Babfile", "path", path)
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w in %s", errs.ErrBabfileNotFound, dir)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/adrg/xdg"
 	"github.com/bab-sh/bab/internal/errs"
 )
 
@@ -164,3 +165,101 @@ func TestFindBabfilePriority(t *testing.T) {
 		}
 	})
 }
+
+func TestFindBabfiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"Babfile.yml":              "",
+		".gitignore":               "node_modules/\n/build\n*.tmp/\n!keep.tmp/\n",
+		"api/Babfile.yml":          "",
+		"api/deep/nested/Babfile":  "",
+		"web/babfile.yaml":         "",
+		"web/.gitignore":           "vendor\n",
+		"web/vendor/Babfile.yml":   "",
+		"node_modules/Babfile.yml": "",
+		"build/Babfile.yml":        "",
+		"docs/build/Babfile.yml":   "",
+		"cache.tmp/Babfile.yml":    "",
+		"keep.tmp/Babfile.yml":     "",
+		".git/Babfile.yml":         "",
+		"empty/README.md":          "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rel := func(paths []string) []string {
+		out := make([]string, len(paths))
+		for i, p := range paths {
+			r, err := filepath.Rel(root, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out[i] = filepath.ToSlash(r)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     []string
+	}{
+		{
+			name:     "unlimited",
+			maxDepth: -1,
+			want:     []string{"Babfile.yml", "api/Babfile.yml", "api/deep/nested/Babfile", "docs/build/Babfile.yml", "keep.tmp/Babfile.yml", "web/babfile.yaml"},
+		},
+		{
+			name:     "current directory only",
+			maxDepth: 0,
+			want:     []string{"Babfile.yml"},
+		},
+		{
+			name:     "one level",
+			maxDepth: 1,
+			want:     []string{"Babfile.yml", "api/Babfile.yml", "keep.tmp/Babfile.yml", "web/babfile.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := FindBabfiles(root, tt.maxDepth)
+			if err != nil {
+				t.Fatalf("FindBabfiles() error: %v", err)
+			}
+			got := rel(found)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FindBabfiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindGlobalBabfile(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	xdg.Reload()
+
+	if _, err := FindGlobalBabfile(); !errors.Is(err, errs.ErrBabfileNotFound) {
+		t.Errorf("expected ErrBabfileNotFound, got %v", err)
+	}
+
+	want := filepath.Join(configHome, "bab", "Babfile.yml")
+	if err := os.MkdirAll(filepath.Dir(want), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(want, []byte("tasks: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FindGlobalBabfile()
+	if err != nil || got != want {
+		t.Errorf("FindGlobalBabfile() = %q, %v, want %q", got, err, want)
+	}
+}
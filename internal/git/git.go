@@ -0,0 +1,106 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func Branch(dir string) (string, error) {
+	return run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func SHA(dir string) (string, error) {
+	return run(dir, "rev-parse", "HEAD")
+}
+
+func Mirror(url, dir string) error {
+	_, err := run("", "clone", "--mirror", "--quiet", url, dir)
+	return err
+}
+
+func Fetch(dir string) error {
+	_, err := run(dir, "fetch", "--prune", "--quiet", "origin")
+	return err
+}
+
+func ResolveCommit(dir, ref string) (string, error) {
+	return run(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+func HasCommit(dir, commit string) bool {
+	_, err := run(dir, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+func Export(gitDir, commit, dest string) error {
+	env := []string{"GIT_INDEX_FILE=" + dest + ".index"}
+	defer func() { _ = os.Remove(dest + ".index") }()
+	if _, err := runEnv(env, "", "--git-dir", gitDir, "--work-tree", dest, "read-tree", commit); err != nil {
+		return err
+	}
+	_, err := runEnv(env, "", "--git-dir", gitDir, "--work-tree", dest, "checkout-index", "--all", "--force")
+	return err
+}
+
+func TopLevel(dir string) (string, error) {
+	return run(dir, "rev-parse", "--show-toplevel")
+}
+
+func MergeBase(dir, a, b string) (string, error) {
+	return run(dir, "merge-base", a, b)
+}
+
+func ChangedFiles(dir, commit string) ([]string, error) {
+	diff, err := run(dir, "-c", "core.quotepath=off", "diff", "--name-only", "--no-renames", commit)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(dir, "-c", "core.quotepath=off", "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, out := range []string{diff, untracked} {
+		if out != "" {
+			files = append(files, strings.Split(out, "\n")...)
+		}
+	}
+	return files, nil
+}
+
+func run(dir string, args ...string) (string, error) {
+	return runEnv(nil, dir, args...)
+}
+
+func runEnv(env []string, dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), firstLine(msg))
+			}
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
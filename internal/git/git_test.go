@@ -0,0 +1,113 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=bab", "-c", "user.email=bab@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestBranchAndSHA(t *testing.T) {
+	dir := initRepo(t)
+
+	branch, err := Branch(dir)
+	if err != nil {
+		t.Fatalf("Branch() error: %v", err)
+	}
+	if branch != "main" {
+		t.Errorf("Branch() = %q, want %q", branch, "main")
+	}
+
+	sha, err := SHA(dir)
+	if err != nil {
+		t.Fatalf("SHA() error: %v", err)
+	}
+	if len(sha) != 40 {
+		t.Errorf("SHA() = %q, want a 40 character hash", sha)
+	}
+}
+
+func TestNotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := Branch(t.TempDir()); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := initRepo(t)
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=bab", "-c", "user.email=bab@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("api/main.go")
+	write("web/index.html")
+	gitRun("add", "-A")
+	gitRun("commit", "-q", "-m", "base")
+	base, err := SHA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("api/handler.go")
+	gitRun("add", "-A")
+	gitRun("commit", "-q", "-m", "change api")
+	write("web/index.html.tmp")
+	write("docs/README.md")
+
+	top, err := TopLevel(filepath.Join(dir, "api"))
+	if err != nil {
+		t.Fatalf("TopLevel() error: %v", err)
+	}
+	mergeBase, err := MergeBase(top, base, "HEAD")
+	if err != nil || mergeBase != base {
+		t.Fatalf("MergeBase() = %q, %v, want %q", mergeBase, err, base)
+	}
+
+	files, err := ChangedFiles(top, mergeBase)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error: %v", err)
+	}
+	slices.Sort(files)
+	want := []string{"api/handler.go", "docs/README.md", "web/index.html.tmp"}
+	if !slices.Equal(files, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, want)
+	}
+}
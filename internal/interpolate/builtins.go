@@ -0,0 +1,91 @@
+package interpolate
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bab-sh/bab/internal/git"
+)
+
+var (
+	startTime = time.Now()
+
+	gitMu    sync.Mutex
+	gitCache = make(map[string]gitResult)
+)
+
+type gitResult struct {
+	value string
+	err   error
+}
+
+var reservedNamespaces = []string{"bab", "git"}
+
+var builtins = map[string]func(ctx *Context) (string, error){
+	"bab.os":        func(ctx *Context) (string, error) { goos, _ := ctx.OSArch(); return goos, nil },
+	"bab.arch":      func(ctx *Context) (string, error) { _, goarch := ctx.OSArch(); return goarch, nil },
+	"bab.root":      func(ctx *Context) (string, error) { return rootDir(ctx), nil },
+	"bab.cwd":       func(ctx *Context) (string, error) { return invocationDir(ctx), nil },
+	"bab.task":      func(ctx *Context) (string, error) { return ctx.Task, nil },
+	"bab.version":   func(ctx *Context) (string, error) { return cmp.Or(ctx.Version, "dev"), nil },
+	"bab.timestamp": func(*Context) (string, error) { return startTime.UTC().Format(time.RFC3339), nil },
+	"bab.cpus":      func(*Context) (string, error) { return strconv.Itoa(runtime.NumCPU()), nil },
+	"bab.profile":   func(ctx *Context) (string, error) { return ctx.Profile, nil },
+	"git.branch":    func(ctx *Context) (string, error) { return gitValue("git.branch", rootDir(ctx), git.Branch) },
+	"git.sha":       func(ctx *Context) (string, error) { return gitValue("git.sha", rootDir(ctx), git.SHA) },
+}
+
+func IsReserved(name string) bool {
+	for _, ns := range reservedNamespaces {
+		if name == ns || strings.HasPrefix(name, ns+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	return names
+}
+
+func rootDir(ctx *Context) string {
+	if ctx.Path == "" {
+		return invocationDir(ctx)
+	}
+	return filepath.Dir(ctx.Path)
+}
+
+func invocationDir(ctx *Context) string {
+	if ctx.InvocationDir != "" {
+		return ctx.InvocationDir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+func gitValue(name, dir string, fn func(string) (string, error)) (string, error) {
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	key := name + "\x00" + dir
+	if r, ok := gitCache[key]; ok {
+		return r.value, r.err
+	}
+	value, err := fn(dir)
+	if err != nil {
+		err = fmt.Errorf("resolving %s: %w", name, err)
+	}
+	gitCache[key] = gitResult{value: value, err: err}
+	return value, err
+}
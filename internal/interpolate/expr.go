@@ -0,0 +1,344 @@
+package interpolate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/errs"
+)
+
+type exprTokenKind int
+
+const (
+	exprEOF exprTokenKind = iota
+	exprIdent
+	exprString
+	exprCoalesce
+	exprPipe
+	exprLParen
+	exprRParen
+	exprComma
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+type operand struct {
+	ident   string
+	literal string
+	isIdent bool
+}
+
+type filterCall struct {
+	name string
+	args []string
+}
+
+type expr struct {
+	src      string
+	operands []operand
+	filters  []filterCall
+}
+
+type value struct {
+	str    string
+	list   []string
+	isList bool
+}
+
+func (v value) String() string {
+	if v.isList {
+		return strings.Join(v.list, ",")
+	}
+	return v.str
+}
+
+func (v value) each(fn func(string) string) value {
+	if !v.isList {
+		return value{str: fn(v.str)}
+	}
+	list := make([]string, len(v.list))
+	for i, s := range v.list {
+		list[i] = fn(s)
+	}
+	return value{list: list, isList: true}
+}
+
+type filter struct {
+	arity int
+	apply func(v value, args []string) value
+}
+
+var filters = map[string]filter{
+	"upper": {apply: func(v value, _ []string) value { return v.each(strings.ToUpper) }},
+	"lower": {apply: func(v value, _ []string) value { return v.each(strings.ToLower) }},
+	"trim":  {apply: func(v value, _ []string) value { return v.each(strings.TrimSpace) }},
+	"quote": {apply: func(v value, _ []string) value { return v.each(shellQuote) }},
+	"base":  {apply: func(v value, _ []string) value { return v.each(filepath.Base) }},
+	"dir":   {apply: func(v value, _ []string) value { return v.each(filepath.Dir) }},
+	"replace": {arity: 2, apply: func(v value, args []string) value {
+		return v.each(func(s string) string { return strings.ReplaceAll(s, args[0], args[1]) })
+	}},
+	"join": {arity: 1, apply: func(v value, args []string) value {
+		if !v.isList {
+			return v
+		}
+		return value{str: strings.Join(v.list, args[0])}
+	}},
+	"split": {arity: 1, apply: func(v value, args []string) value {
+		items := v.list
+		if !v.isList {
+			items = []string{v.str}
+		}
+		var list []string
+		for _, item := range items {
+			list = append(list, strings.Split(item, args[0])...)
+		}
+		return value{list: list, isList: true}
+	}},
+}
+
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return cmdQuote(s)
+	}
+	return posixQuote(s)
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cmdQuote quotes s as a single argument for the program started by cmd /C,
+// then escapes every cmd metacharacter with ^ so cmd passes it through as is.
+func cmdQuote(s string) string {
+	var arg strings.Builder
+	arg.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		backslashes := 0
+		for ; i < len(s) && s[i] == '\\'; i++ {
+			backslashes++
+		}
+		switch {
+		case i == len(s):
+			arg.WriteString(strings.Repeat(`\`, 2*backslashes))
+		case s[i] == '"':
+			arg.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+			arg.WriteByte('"')
+		default:
+			arg.WriteString(strings.Repeat(`\`, backslashes))
+			arg.WriteByte(s[i])
+		}
+	}
+	arg.WriteByte('"')
+
+	var b strings.Builder
+	for _, r := range arg.String() {
+		if strings.ContainsRune(`()%!^"<>&|`, r) {
+			b.WriteByte('^')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func filterNames() []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	return names
+}
+
+func lexExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, exprToken{kind: exprString, text: src[i+1 : i+1+end]})
+			i += end + 2
+		case strings.HasPrefix(src[i:], "??"):
+			tokens = append(tokens, exprToken{kind: exprCoalesce, text: "??"})
+			i += 2
+		case c == '|':
+			tokens = append(tokens, exprToken{kind: exprPipe, text: "|"})
+			i++
+		case c == '(':
+			tokens = append(tokens, exprToken{kind: exprLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, exprToken{kind: exprRParen, text: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, exprToken{kind: exprComma, text: ","})
+			i++
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '[' || src[j] == ']') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: exprIdent, text: src[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(c))
+		}
+	}
+	return append(tokens, exprToken{kind: exprEOF}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+func parseExpr(src string) (*expr, error) {
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	e := &expr{src: src}
+	pos := 0
+	next := func() exprToken {
+		tok := tokens[pos]
+		if tok.kind != exprEOF {
+			pos++
+		}
+		return tok
+	}
+
+	for {
+		tok := next()
+		switch tok.kind {
+		case exprIdent:
+			e.operands = append(e.operands, operand{ident: tok.text, isIdent: true})
+		case exprString:
+			e.operands = append(e.operands, operand{literal: tok.text})
+		default:
+			return nil, unexpected(tok, "a variable name or string")
+		}
+		if tokens[pos].kind != exprCoalesce {
+			break
+		}
+		next()
+	}
+
+	for tokens[pos].kind == exprPipe {
+		next()
+		name := next()
+		if name.kind != exprIdent {
+			return nil, unexpected(name, "a filter name")
+		}
+		f, ok := filters[name.text]
+		if !ok {
+			msg := fmt.Sprintf("unknown filter %q", name.text)
+			if suggestion := errs.FindSimilar(name.text, filterNames()); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			return nil, errors.New(msg)
+		}
+
+		call := filterCall{name: name.text}
+		if tokens[pos].kind == exprLParen {
+			next()
+			for tokens[pos].kind != exprRParen {
+				arg := next()
+				if arg.kind != exprString {
+					return nil, unexpected(arg, "a string argument")
+				}
+				call.args = append(call.args, arg.text)
+				if tokens[pos].kind == exprComma {
+					next()
+				} else if tokens[pos].kind != exprRParen {
+					return nil, unexpected(tokens[pos], `"," or ")"`)
+				}
+			}
+			next()
+		}
+		if len(call.args) != f.arity {
+			return nil, fmt.Errorf("filter %q takes %d argument(s), got %d", call.name, f.arity, len(call.args))
+		}
+		e.filters = append(e.filters, call)
+	}
+
+	if tok := tokens[pos]; tok.kind != exprEOF {
+		return nil, unexpected(tok, `"??", "|" or the end of the expression`)
+	}
+	return e, nil
+}
+
+func unexpected(tok exprToken, want string) error {
+	if tok.kind == exprEOF {
+		return fmt.Errorf("expected %s, got end of expression", want)
+	}
+	return fmt.Errorf("expected %s, got %q", want, tok.text)
+}
+
+func (e *expr) refs() []string {
+	var refs []string
+	for _, op := range e.operands {
+		if op.isIdent {
+			refs = append(refs, op.ident)
+		}
+	}
+	return refs
+}
+
+func (e *expr) eval(ctx *Context) (string, error) {
+	raw, err := e.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	return Render(raw), nil
+}
+
+func (e *expr) resolve(ctx *Context) (any, error) {
+	var raw any
+	for i, op := range e.operands {
+		if !op.isIdent {
+			raw = op.literal
+			break
+		}
+		last := i == len(e.operands)-1
+		v, err := Lookup(op.ident, ctx)
+		if err != nil && last {
+			return nil, err
+		}
+		if err != nil || (Render(v) == "" && !last) {
+			continue
+		}
+		raw = v
+		break
+	}
+
+	if len(e.filters) == 0 {
+		return raw, nil
+	}
+	v := toValue(raw)
+	for _, call := range e.filters {
+		v = filters[call.name].apply(v, call.args)
+	}
+	if !v.isList {
+		return v.str, nil
+	}
+	list := make([]any, len(v.list))
+	for i, item := range v.list {
+		list[i] = item
+	}
+	return list, nil
+}
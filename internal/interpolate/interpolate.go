@@ -1,38 +1,52 @@
 package interpolate
 
 import (
-	"os"
 	"regexp"
+	"runtime"
 	"strings"
 
 	"github.com/bab-sh/bab/internal/errs"
 )
 
-var Pattern = regexp.MustCompile(`\$\{\{\s*([a-zA-Z_][a-zA-Z0-9_.]*)\s*\}\}`)
+var Pattern = regexp.MustCompile(`\$\{\{\s*(.+?)\s*\}\}`)
 var EscapePattern = regexp.MustCompile(`\$\$\{\{`)
 
 const escapePlaceholder = "\x00ESCAPED_BRACE\x00"
 
 type Context struct {
-	Vars map[string]string
-	Path string
-	Line int
+	Vars          map[string]any
+	Path          string
+	Line          int
+	Task          string
+	Version       string
+	InvocationDir string
+	Profile       string
+	Base          string
+	Platform      string
 }
 
-func NewContext(vars map[string]string) *Context {
+func NewContext(vars map[string]any) *Context {
 	if vars == nil {
-		vars = make(map[string]string)
+		vars = make(map[string]any)
 	}
 	return &Context{Vars: vars}
 }
 
-func NewContextWithLocation(vars map[string]string, path string, line int) *Context {
+func NewContextWithLocation(vars map[string]any, path string, line int) *Context {
 	ctx := NewContext(vars)
 	ctx.Path = path
 	ctx.Line = line
 	return ctx
 }
 
+func (ctx *Context) OSArch() (goos, goarch string) {
+	if ctx.Platform == "" {
+		return runtime.GOOS, runtime.GOARCH
+	}
+	goos, goarch, _ = strings.Cut(ctx.Platform, "/")
+	return goos, goarch
+}
+
 func Interpolate(input string, ctx *Context) (string, error) {
 	if ctx == nil {
 		ctx = NewContext(nil)
@@ -47,9 +61,7 @@ func Interpolate(input string, ctx *Context) (string, error) {
 		if len(submatch) < 2 {
 			return match
 		}
-		name := submatch[1]
-
-		value, err := resolveVar(name, ctx)
+		value, err := evalExpr(submatch[1], ctx)
 		if err != nil {
 			errs = append(errs, err)
 			return match
@@ -65,42 +77,47 @@ func Interpolate(input string, ctx *Context) (string, error) {
 	return result, nil
 }
 
-func resolveVar(name string, ctx *Context) (string, error) {
-	if strings.HasPrefix(name, "env.") {
-		envName := strings.TrimPrefix(name, "env.")
-		return os.Getenv(envName), nil
-	}
-
-	if value, ok := ctx.Vars[name]; ok {
-		return value, nil
+func Resolve(input string, ctx *Context) (any, error) {
+	if ctx == nil {
+		ctx = NewContext(nil)
 	}
-
-	available := make([]string, 0, len(ctx.Vars))
-	for k := range ctx.Vars {
-		available = append(available, k)
+	trimmed := strings.TrimSpace(input)
+	if loc := Pattern.FindStringSubmatchIndex(trimmed); loc != nil && loc[0] == 0 && loc[1] == len(trimmed) {
+		e, err := parseExpr(trimmed[loc[2]:loc[3]])
+		if err != nil {
+			return nil, &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: trimmed[loc[2]:loc[3]], Err: err}
+		}
+		return e.resolve(ctx)
 	}
+	return Interpolate(input, ctx)
+}
 
-	return "", &errs.VarNotFoundError{
-		Path:      ctx.Path,
-		Line:      ctx.Line,
-		Name:      name,
-		Available: available,
+func evalExpr(src string, ctx *Context) (string, error) {
+	e, err := parseExpr(src)
+	if err != nil {
+		return "", &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: src, Err: err}
 	}
+	return e.eval(ctx)
 }
 
-func ResolveVars(vars map[string]string, parentVars map[string]string) (map[string]string, error) {
+func ResolveVars(vars map[string]any, parentVars map[string]any) (map[string]any, error) {
 	return ResolveVarsWithLocation(vars, parentVars, "", 0)
 }
 
-func ResolveVarsWithLocation(vars map[string]string, parentVars map[string]string, path string, line int) (map[string]string, error) {
+func ResolveVarsWithLocation(vars map[string]any, parentVars map[string]any, path string, line int) (map[string]any, error) {
+	return ResolveVarsInContext(vars, parentVars, NewContextWithLocation(nil, path, line))
+}
+
+func ResolveVarsInContext(vars map[string]any, parentVars map[string]any, base *Context) (map[string]any, error) {
+	path, line := base.Path, base.Line
 	if vars == nil {
 		if parentVars == nil {
-			return make(map[string]string), nil
+			return make(map[string]any), nil
 		}
 		return parentVars, nil
 	}
 
-	resolved := make(map[string]string)
+	resolved := make(map[string]any)
 	for k, v := range parentVars {
 		resolved[k] = v
 	}
@@ -122,8 +139,8 @@ func ResolveVarsWithLocation(vars map[string]string, parentVars map[string]strin
 		return names
 	}
 
-	var resolveOne func(name string) (string, error)
-	resolveOne = func(name string) (string, error) {
+	var resolveOne func(name string) (any, error)
+	resolveOne = func(name string) (any, error) {
 		if resolvedSet[name] {
 			return resolved[name], nil
 		}
@@ -132,7 +149,7 @@ func ResolveVarsWithLocation(vars map[string]string, parentVars map[string]strin
 			chain := make([]string, len(resolvingStack)+1)
 			copy(chain, resolvingStack)
 			chain[len(resolvingStack)] = name
-			return "", &errs.VarCycleError{Path: path, Line: line, Name: name, Chain: chain}
+			return nil, &errs.VarCycleError{Path: path, Line: line, Name: name, Chain: chain}
 		}
 
 		raw, exists := vars[name]
@@ -140,37 +157,33 @@ func ResolveVarsWithLocation(vars map[string]string, parentVars map[string]strin
 			if v, ok := parentVars[name]; ok {
 				return v, nil
 			}
-			return "", &errs.VarNotFoundError{Path: path, Line: line, Name: name, Available: allVarNames()}
+			return nil, &errs.VarNotFoundError{Path: path, Line: line, Name: name, Available: allVarNames()}
 		}
 
 		resolving[name] = true
 		resolvingStack = append(resolvingStack, name)
 
-		matches := Pattern.FindAllStringSubmatch(raw, -1)
-		for _, match := range matches {
-			if len(match) < 2 {
-				continue
-			}
-			refName := match[1]
-
-			if strings.HasPrefix(refName, "env.") {
+		for _, ref := range valueRefs(raw) {
+			if strings.HasPrefix(ref, "env.") {
 				continue
 			}
 
+			refName := RootName(ref)
 			if _, ok := vars[refName]; ok {
 				refValue, err := resolveOne(refName)
 				if err != nil {
-					return "", err
+					return nil, err
 				}
 				resolved[refName] = refValue
 				resolvedSet[refName] = true
 			}
 		}
 
-		ctx := NewContextWithLocation(resolved, path, line)
-		value, err := Interpolate(raw, ctx)
+		ctx := *base
+		ctx.Vars = resolved
+		value, err := ResolveValue(raw, &ctx)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		resolvingStack = resolvingStack[:len(resolvingStack)-1]
@@ -198,8 +211,11 @@ func ExtractVarRefs(s string) []string {
 	matches := Pattern.FindAllStringSubmatch(s, -1)
 	refs := make([]string, 0, len(matches))
 	for _, match := range matches {
-		if len(match) >= 2 {
-			refs = append(refs, match[1])
+		if len(match) < 2 {
+			continue
+		}
+		if e, err := parseExpr(match[1]); err == nil {
+			refs = append(refs, e.refs()...)
 		}
 	}
 	return refs
@@ -2,14 +2,19 @@ package interpolate
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bab-sh/bab/internal/errs"
 )
 
 func TestInterpolate_BasicVariable(t *testing.T) {
-	ctx := NewContext(map[string]string{
+	ctx := NewContext(map[string]any{
 		"name":    "bab",
 		"version": "1.0.0",
 	})
@@ -69,10 +74,283 @@ func TestInterpolate_EnvAccess(t *testing.T) {
 	}
 }
 
+func TestInterpolate_Builtins(t *testing.T) {
+	ctx := NewContextWithLocation(map[string]any{"bab": "shadowed"}, filepath.Join("/work", "app", "Babfile.yml"), 3)
+	ctx.Task = "build:api"
+	ctx.Version = "1.2.3"
+	ctx.InvocationDir = filepath.Join("/work", "app", "web")
+	ctx.Platform = "windows/arm64"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"${{ bab.os }}/${{ bab.arch }}", "windows/arm64"},
+		{"${{ bab.root }}", filepath.Join("/work", "app")},
+		{"${{ bab.cwd }}", filepath.Join("/work", "app", "web")},
+		{"${{ bab.task }}", "build:api"},
+		{"${{ bab.version }}", "1.2.3"},
+		{"${{ bab.cpus }}", strconv.Itoa(runtime.NumCPU())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := Interpolate(tt.input, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dev " + cwd + " " + runtime.GOOS + "/" + runtime.GOARCH
+	if got, err := Interpolate("${{ bab.version }} ${{ bab.cwd }} ${{ bab.os }}/${{ bab.arch }}", nil); err != nil || got != want {
+		t.Errorf("expected defaults %q, got %q, %v", want, got, err)
+	}
+
+	timestamp, err := Interpolate("${{ bab.timestamp }}", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		t.Errorf("bab.timestamp %q is not RFC 3339: %v", timestamp, err)
+	}
+
+	for _, input := range []string{"${{ bab }}", "${{ bab.unknown }}"} {
+		if _, err := Interpolate(input, ctx); !errors.Is(err, errs.ErrVarNotFound) {
+			t.Errorf("Interpolate(%q): expected variable not found error, got %v", input, err)
+		}
+	}
+}
+
+func TestInterpolate_Expressions(t *testing.T) {
+	t.Setenv("BAB_TEST_PORT", "")
+
+	ctx := NewContext(map[string]any{
+		"name":    "Bab Runner",
+		"empty":   "",
+		"file":    "/srv/app/config.yml",
+		"targets": "api,web",
+		"message": "it's done",
+	})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"default for undefined", "${{ port ?? '8080' }}", "8080"},
+		{"default for empty", "${{ empty ?? \"none\" }}", "none"},
+		{"default for empty env", "${{ env.BAB_TEST_PORT ?? '3000' }}", "3000"},
+		{"default not used", "${{ name ?? 'x' }}", "Bab Runner"},
+		{"default chain", "${{ missing ?? empty ?? name }}", "Bab Runner"},
+		{"string literal", "${{ 'literal' }}", "literal"},
+		{"upper", "${{ name | upper }}", "BAB RUNNER"},
+		{"lower", "${{ name | lower }}", "bab runner"},
+		{"trim", "${{ ' padded ' | trim }}", "padded"},
+		{"replace", "${{ name | replace(' ', '-') | lower }}", "bab-runner"},
+		{"quote", "${{ message | quote }}", `'it'\''s done'`},
+		{"base", "${{ file | base }}", "config.yml"},
+		{"dir", "${{ file | dir }}", filepath.Dir("/srv/app/config.yml")},
+		{"split and join", "${{ targets | split(',') | join(' ') }}", "api web"},
+		{"split and quote", "${{ targets | split(',') | quote | join(' ') }}", "'api' 'web'"},
+		{"default with filter", "${{ region ?? 'eu' | upper }}", "EU"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Interpolate(tt.input, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	for input, want := range map[string]string{
+		"api":                 `^"api^"`,
+		`it's "done" & 100%`:  `^"it's \^"done\^" ^& 100^%^"`,
+		`C:\dir\`:             `^"C:\dir\\^"`,
+		`a\"b | (c) > d ^ e!`: `^"a\\\^"b ^| ^(c^) ^> d ^^ e^!^"`,
+	} {
+		if got := cmdQuote(input); got != want {
+			t.Errorf("cmdQuote(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestInterpolate_ExpressionErrors(t *testing.T) {
+	ctx := NewContextWithLocation(map[string]any{"name": "bab"}, "Babfile.yml", 4)
+
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{"${{ name | uppr }}", `unknown filter "uppr" (did you mean "upper"?)`},
+		{"${{ name | replace('a') }}", `filter "replace" takes 2 argument(s), got 1`},
+		{"${{ name ?? }}", "expected a variable name or string, got end of expression"},
+		{"${{ 'open }}", "unterminated string"},
+		{"${{ name name }}", `expected "??", "|" or the end of the expression, got "name"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Interpolate(tt.input, ctx)
+			var exprErr *errs.ExprError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("expected ExprError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got %q", tt.message, err.Error())
+			}
+			if path, line := errs.Locate(err); path != "Babfile.yml" || line != 4 {
+				t.Errorf("expected location Babfile.yml:4, got %s:%d", path, line)
+			}
+		})
+	}
+
+	if _, err := Interpolate("${{ nme ?? other | upper }}", ctx); !errors.Is(err, errs.ErrVarNotFound) {
+		t.Errorf("expected variable not found error, got %v", err)
+	}
+}
+
+func TestInterpolate_StructuredVars(t *testing.T) {
+	ctx := NewContext(map[string]any{
+		"services": []any{"api", "web"},
+		"db":       map[string]any{"host": "localhost", "port": "5432"},
+		"matrix":   []any{map[string]any{"os": "linux"}},
+	})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"list", "${{ services }}", "api,web"},
+		{"index", "${{ services[1] }}", "web"},
+		{"map key", "${{ db.host }}:${{ db.port }}", "localhost:5432"},
+		{"map", "${{ db }}", `{"host":"localhost","port":"5432"}`},
+		{"nested", "${{ matrix[0].os }}", "linux"},
+		{"list filter", "${{ services | upper | join(' ') }}", "API WEB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Interpolate(tt.input, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	errTests := []struct {
+		input   string
+		message string
+	}{
+		{"${{ services[2] }}", "index 2 out of range for services with 2 item(s)"},
+		{"${{ services.name }}", "services is a list, use an index like services[0]"},
+		{"${{ db.hots }}", `did you mean "db.host"?`},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Interpolate(tt.input, ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestResolve_RawValue(t *testing.T) {
+	ctx := NewContext(map[string]any{"services": []any{"api", "web"}})
+
+	raw, err := Resolve("${{ services }}", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list, ok := raw.([]any); !ok || len(list) != 2 {
+		t.Errorf("expected raw list, got %#v", raw)
+	}
+
+	raw, err = Resolve("all: ${{ services }}", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw != "all: api,web" {
+		t.Errorf("expected rendered string, got %#v", raw)
+	}
+}
+
+func TestItems(t *testing.T) {
+	items := Items(map[string]any{"b": "2", "a": "1"})
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	first := items[0].(map[string]any)
+	if first["key"] != "a" || first["value"] != "1" {
+		t.Errorf("expected sorted key/value item, got %#v", first)
+	}
+	if got := Items(""); got != nil {
+		t.Errorf("expected no items for empty value, got %#v", got)
+	}
+	if got := Items("one"); len(got) != 1 {
+		t.Errorf("expected single item, got %#v", got)
+	}
+}
+
+func TestInterpolate_BuiltinSuggestion(t *testing.T) {
+	_, err := Interpolate("${{ bab.arc }}", NewContext(nil))
+	if err == nil || !strings.Contains(err.Error(), `did you mean "bab.arch"?`) {
+		t.Errorf("expected suggestion for bab.arch, got %v", err)
+	}
+}
+
+func TestResolveVarsInContext_Task(t *testing.T) {
+	base := NewContext(nil)
+	base.Task = "deploy"
+
+	resolved, err := ResolveVarsInContext(map[string]any{"log": "logs/${{ bab.task }}.log"}, nil, base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved["log"] != "logs/deploy.log" {
+		t.Errorf("expected %q, got %q", "logs/deploy.log", resolved["log"])
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	for name, want := range map[string]bool{
+		"bab":        true,
+		"bab.os":     true,
+		"git":        true,
+		"git.branch": true,
+		"babel":      false,
+		"github":     false,
+		"env":        false,
+	} {
+		if got := IsReserved(name); got != want {
+			t.Errorf("IsReserved(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
 func TestInterpolate_MixedVarsAndEnv(t *testing.T) {
 	t.Setenv("BAB_ENV", "production")
 
-	ctx := NewContext(map[string]string{
+	ctx := NewContext(map[string]any{
 		"app": "myapp",
 	})
 
@@ -89,7 +367,7 @@ func TestInterpolate_MixedVarsAndEnv(t *testing.T) {
 }
 
 func TestInterpolate_Escaping(t *testing.T) {
-	ctx := NewContext(map[string]string{
+	ctx := NewContext(map[string]any{
 		"name": "bab",
 	})
 
@@ -117,7 +395,7 @@ func TestInterpolate_Escaping(t *testing.T) {
 }
 
 func TestInterpolate_UndefinedVar(t *testing.T) {
-	ctx := NewContext(map[string]string{
+	ctx := NewContext(map[string]any{
 		"name": "bab",
 	})
 
@@ -141,7 +419,7 @@ func TestInterpolate_UndefinedVar(t *testing.T) {
 }
 
 func TestInterpolate_UndefinedVarSuggestion(t *testing.T) {
-	ctx := NewContext(map[string]string{
+	ctx := NewContext(map[string]any{
 		"app_name":    "myapp",
 		"app_version": "1.0.0",
 	})
@@ -172,7 +450,7 @@ func TestInterpolate_NilContext(t *testing.T) {
 }
 
 func TestResolveVars_Basic(t *testing.T) {
-	vars := map[string]string{
+	vars := map[string]any{
 		"name":    "bab",
 		"version": "1.0.0",
 	}
@@ -191,7 +469,7 @@ func TestResolveVars_Basic(t *testing.T) {
 }
 
 func TestResolveVars_References(t *testing.T) {
-	vars := map[string]string{
+	vars := map[string]any{
 		"base":   "/app",
 		"build":  "${{ base }}/build",
 		"output": "${{ build }}/bin",
@@ -214,11 +492,11 @@ func TestResolveVars_References(t *testing.T) {
 }
 
 func TestResolveVars_WithParent(t *testing.T) {
-	parent := map[string]string{
+	parent := map[string]any{
 		"global": "parent_value",
 	}
 
-	vars := map[string]string{
+	vars := map[string]any{
 		"local": "${{ global }}_child",
 	}
 
@@ -238,7 +516,7 @@ func TestResolveVars_WithParent(t *testing.T) {
 func TestResolveVars_EnvReference(t *testing.T) {
 	t.Setenv("BAB_HOME", "/home/bab")
 
-	vars := map[string]string{
+	vars := map[string]any{
 		"home": "${{ env.BAB_HOME }}",
 		"data": "${{ home }}/data",
 	}
@@ -257,7 +535,7 @@ func TestResolveVars_EnvReference(t *testing.T) {
 }
 
 func TestResolveVars_CycleDetection(t *testing.T) {
-	vars := map[string]string{
+	vars := map[string]any{
 		"a": "${{ b }}",
 		"b": "${{ a }}",
 	}
@@ -283,7 +561,7 @@ func TestResolveVars_CycleDetection(t *testing.T) {
 }
 
 func TestResolveVars_SelfReference(t *testing.T) {
-	vars := map[string]string{
+	vars := map[string]any{
 		"a": "${{ a }}",
 	}
 
@@ -327,6 +605,8 @@ func TestExtractVarRefs(t *testing.T) {
 		{"${{ var }}", []string{"var"}},
 		{"${{ a }} ${{ b }}", []string{"a", "b"}},
 		{"${{ env.HOME }}", []string{"env.HOME"}},
+		{"${{ port ?? fallback | upper }}", []string{"port", "fallback"}},
+		{"${{ 'literal' | quote }}", []string{}},
 		{"no vars", []string{}},
 	}
 
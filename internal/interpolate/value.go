@@ -0,0 +1,270 @@
+package interpolate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/errs"
+)
+
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func Render(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			if !isScalar(item) {
+				return renderJSON(v)
+			}
+			items[i] = Render(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		return renderJSON(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case []any, map[string]any:
+		return false
+	default:
+		return true
+	}
+}
+
+func renderJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func Lookup(name string, ctx *Context) (any, error) {
+	if ctx == nil {
+		ctx = NewContext(nil)
+	}
+
+	if strings.HasPrefix(name, "env.") {
+		return os.Getenv(strings.TrimPrefix(name, "env.")), nil
+	}
+
+	if IsReserved(name) {
+		if fn, ok := builtins[name]; ok {
+			return fn(ctx)
+		}
+		return nil, notFound(name, ctx, BuiltinNames())
+	}
+
+	root, segments, err := splitPath(name)
+	if err != nil {
+		return nil, &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: name, Err: err}
+	}
+
+	current, ok := ctx.Vars[root]
+	if !ok {
+		available := make([]string, 0, len(ctx.Vars)+len(builtins))
+		for k := range ctx.Vars {
+			available = append(available, k)
+		}
+		return nil, notFound(root, ctx, append(available, BuiltinNames()...))
+	}
+
+	walked := root
+	for _, seg := range segments {
+		switch v := current.(type) {
+		case []any:
+			if !seg.isIndex {
+				return nil, &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: name, Err: fmt.Errorf("%s is a list, use an index like %s[0]", walked, walked)}
+			}
+			if seg.index >= len(v) {
+				return nil, &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: name, Err: fmt.Errorf("index %d out of range for %s with %d item(s)", seg.index, walked, len(v))}
+			}
+			current = v[seg.index]
+			walked += fmt.Sprintf("[%d]", seg.index)
+		case map[string]any:
+			key := seg.key
+			if seg.isIndex {
+				key = strconv.Itoa(seg.index)
+			}
+			next, ok := v[key]
+			if !ok {
+				keys := make([]string, 0, len(v))
+				for k := range v {
+					keys = append(keys, walked+"."+k)
+				}
+				return nil, notFound(walked+"."+key, ctx, keys)
+			}
+			current = next
+			walked += "." + key
+		default:
+			return nil, &errs.ExprError{Path: ctx.Path, Line: ctx.Line, Expr: name, Err: fmt.Errorf("%s is not a list or map", walked)}
+		}
+	}
+	return current, nil
+}
+
+func notFound(name string, ctx *Context, available []string) error {
+	return &errs.VarNotFoundError{
+		Path:      ctx.Path,
+		Line:      ctx.Line,
+		Name:      name,
+		Available: available,
+	}
+}
+
+func splitPath(name string) (string, []pathSegment, error) {
+	end := strings.IndexAny(name, ".[")
+	if end < 0 {
+		return name, nil, nil
+	}
+
+	root := name[:end]
+	var segments []pathSegment
+	rest := name[end:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+			if n < 0 {
+				n = len(rest)
+			}
+			if n == 0 {
+				return "", nil, fmt.Errorf("empty key in %q", name)
+			}
+			segments = append(segments, pathSegment{key: rest[:n]})
+			rest = rest[n:]
+		case '[':
+			closing := strings.IndexByte(rest, ']')
+			if closing < 0 {
+				return "", nil, fmt.Errorf("missing ] in %q", name)
+			}
+			index, err := strconv.Atoi(rest[1:closing])
+			if err != nil || index < 0 {
+				return "", nil, fmt.Errorf("invalid index %q in %q", rest[1:closing], name)
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			rest = rest[closing+1:]
+		default:
+			return "", nil, fmt.Errorf("unexpected %q in %q", rest[:1], name)
+		}
+	}
+	return root, segments, nil
+}
+
+func RootName(ref string) string {
+	if strings.HasPrefix(ref, "env.") || IsReserved(ref) {
+		return ref
+	}
+	if end := strings.IndexAny(ref, ".["); end >= 0 {
+		return ref[:end]
+	}
+	return ref
+}
+
+func toValue(v any) value {
+	list, ok := v.([]any)
+	if !ok {
+		return value{str: Render(v)}
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = Render(item)
+	}
+	return value{list: items, isList: true}
+}
+
+func ResolveValue(v any, ctx *Context) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return Interpolate(v, ctx)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			resolved, err := ResolveValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			resolved, err := ResolveValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func valueRefs(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return ExtractVarRefs(v)
+	case []any:
+		var refs []string
+		for _, item := range v {
+			refs = append(refs, valueRefs(item)...)
+		}
+		return refs
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var refs []string
+		for _, k := range keys {
+			refs = append(refs, valueRefs(v[k])...)
+		}
+		return refs
+	default:
+		return nil
+	}
+}
+
+func Items(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]any, len(keys))
+		for i, k := range keys {
+			items[i] = map[string]any{"key": k, "value": v[k]}
+		}
+		return items
+	default:
+		if Render(v) == "" {
+			return nil
+		}
+		return []any{v}
+	}
+}
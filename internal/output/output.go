@@ -25,12 +25,8 @@ var (
 
 var Writer io.Writer = os.Stderr
 
-func Task(name string) {
-	_, _ = fmt.Fprintf(Writer, "%s %s %s\n",
-		taskIndicator.Render("●"),
-		taskAction.Render("Running"),
-		taskName.Render(name),
-	)
+func Task(name, profile string) {
+	_, _ = fmt.Fprintln(Writer, RenderTask(name, profile))
 }
 
 func Cmd(cmd string) {
@@ -47,12 +43,16 @@ func Dep(name string) {
 	)
 }
 
-func RenderTask(name string) string {
-	return fmt.Sprintf("%s %s %s",
+func RenderTask(name, profile string) string {
+	line := fmt.Sprintf("%s %s %s",
 		taskIndicator.Render("●"),
 		taskAction.Render("Running"),
 		taskName.Render(name),
 	)
+	if profile != "" {
+		line += " " + secondary.Render("["+profile+"]")
+	}
+	return line
 }
 
 func RenderCmd(cmd string) string {
@@ -116,3 +116,37 @@ func ParallelDone(labels []string, errs []error) {
 		_, _ = fmt.Fprintln(Writer, s)
 	}
 }
+
+func RenderProjectsDone(labels []string, errs []error) string {
+	if len(errs) != len(labels) {
+		return ""
+	}
+	passed, failed := 0, 0
+	rows := make([]string, len(labels))
+	for i, label := range labels {
+		switch {
+		case errs[i] == nil:
+			passed++
+			rows[i] = "  " + parallelSuccess.Render("✓ "+label)
+		case errors.Is(errs[i], context.Canceled):
+			rows[i] = "  " + parallelCancelled.Render("⊘ "+label)
+		default:
+			failed++
+			rows[i] = "  " + parallelFailure.Render("✗ "+label)
+		}
+	}
+
+	header := fmt.Sprintf("%s %s %s",
+		taskIndicator.Render("●"),
+		taskAction.Render("Projects"),
+		secondary.Render(fmt.Sprintf("%d passed, %d failed", passed, failed)),
+	)
+	return header + "\n" + strings.Join(rows, "\n")
+}
+
+func ProjectsDone(labels []string, errs []error) {
+	s := RenderProjectsDone(labels, errs)
+	if s != "" {
+		_, _ = fmt.Fprintln(Writer, s)
+	}
+}
@@ -219,6 +219,23 @@ func TestDuplicateTaskError_Error(t *testing.T) {
 	}
 }
 
+func TestDuplicateTaskError_ErrorOtherFile(t *testing.T) {
+	cwd, _ := os.Getwd()
+	err := &errs.DuplicateTaskError{
+		Path:         filepath.Join(cwd, ".bab", "build.yml"),
+		Line:         3,
+		TaskName:     "build",
+		OriginalPath: filepath.Join(cwd, "Babfile.yml"),
+		OriginalLine: 5,
+	}
+
+	got := err.Error()
+	want := filepath.Join(".bab", "build.yml") + `:3: duplicate task "build" (first defined at Babfile.yml:5)`
+	if got != want {
+		t.Errorf("expected %q, got: %s", want, got)
+	}
+}
+
 func TestDuplicateTaskError_Is(t *testing.T) {
 	err := &errs.DuplicateTaskError{TaskName: "build"}
 
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/errs"
+)
+
+type extendsResolver struct {
+	path      string
+	bases     babfile.TaskMap
+	templates map[string]bool
+	resolved  map[string]bool
+	stack     []string
+	verrs     *errs.ValidationErrors
+}
+
+func resolveExtends(path string, tasks babfile.TaskMap, templates map[string]babfile.Task, verrs *errs.ValidationErrors) {
+	r := &extendsResolver{
+		path:      path,
+		bases:     maps.Clone(tasks),
+		templates: make(map[string]bool, len(templates)),
+		resolved:  make(map[string]bool),
+		verrs:     verrs,
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(templates)) {
+		tmpl := templates[name]
+		tmpl.Name = name
+		tmpl.SourcePath = path
+		if _, ok := tasks[name]; ok {
+			r.verrs.Add(&errs.ParseError{Path: path, Line: tmpl.Line, Message: fmt.Sprintf("template %q conflicts with a task of the same name", name)})
+			continue
+		}
+		r.bases[name] = &tmpl
+		r.templates[name] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(r.bases)) {
+		r.resolve(name)
+	}
+}
+
+func (r *extendsResolver) kind(name string) string {
+	if r.templates[name] {
+		return "template"
+	}
+	return "task"
+}
+
+func (r *extendsResolver) resolve(name string) bool {
+	if ok, done := r.resolved[name]; done {
+		return ok
+	}
+	task := r.bases[name]
+	if task.Extends == "" {
+		r.resolved[name] = true
+		return true
+	}
+
+	if i := slices.Index(r.stack, name); i >= 0 {
+		r.verrs.Add(&errs.CircularDepError{
+			Path:  taskPath(r.path, task),
+			Line:  task.ExtendsLine,
+			Type:  "extends",
+			Chain: append(slices.Clone(r.stack[i:]), name),
+		})
+		return false
+	}
+
+	base, ok := r.bases[task.Extends]
+	if !ok {
+		r.verrs.Add(&errs.ParseError{
+			Path:    taskPath(r.path, task),
+			Line:    task.ExtendsLine,
+			Message: fmt.Sprintf("%s %q: extends unknown task or template %q", r.kind(name), name, task.Extends),
+		})
+		r.resolved[name] = false
+		return false
+	}
+
+	r.stack = append(r.stack, name)
+	ok = r.resolve(task.Extends)
+	r.stack = r.stack[:len(r.stack)-1]
+	r.resolved[name] = ok
+	if ok {
+		inherit(task, base)
+	}
+	return ok
+}
+
+func inherit(task, base *babfile.Task) {
+	task.Vars = babfile.MergeVarMaps(base.Vars, task.Vars)
+	task.Env = babfile.MergeEnvMaps(base.Env, task.Env)
+	if task.Dir == "" && base.Dir != "" {
+		task.Dir = base.Dir
+		if base.Root() != task.Root() && !filepath.IsAbs(base.Dir) {
+			task.Dir = filepath.Join(base.Root(), base.Dir)
+		}
+	}
+	if task.Deps == nil {
+		task.Deps = base.Deps
+	}
+	if task.Silent == nil {
+		task.Silent = base.Silent
+	}
+	if task.Output == nil {
+		task.Output = base.Output
+	}
+	if task.Run == nil {
+		task.Run = base.Run
+	}
+	if task.RunAppend != nil {
+		task.Run = append(slices.Clone(task.Run), task.RunAppend...)
+		task.RunAppend = nil
+	}
+	task.Extends = ""
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/charmbracelet/log"
+)
+
+var fragmentDirs = []string{".bab", "Babfile.d"}
+
+var fragmentRootKeys = []string{keyVars, keyVarsFile, keyEnv, keySilent, keyOutput, keyDir, keyIncludes, keyProfiles, keyTemplates}
+
+func findFragments(baseDir string) ([]string, error) {
+	var files []string
+	for _, dir := range fragmentDirs {
+		var matches []string
+		for _, ext := range []string{"*.yml", "*.yaml"} {
+			found, err := filepath.Glob(filepath.Join(baseDir, dir, ext))
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, found...)
+		}
+		slices.Sort(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func mergeFragments(baseDir string, tasks babfile.TaskMap) error {
+	files, err := findFragments(baseDir)
+	if err != nil {
+		return &errs.ParseError{Path: baseDir, Message: "invalid fragment pattern", Cause: err}
+	}
+
+	verrs := &errs.ValidationErrors{}
+	for _, path := range files {
+		log.Debug("Parsing babfile fragment", "path", path)
+		bf, err := readFragment(path)
+		if err != nil {
+			return err
+		}
+		if bf == nil {
+			continue
+		}
+
+		names := make([]string, 0, len(bf.Tasks))
+		for name := range bf.Tasks {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			task := bf.Tasks[name]
+			if existing, ok := tasks[name]; ok {
+				verrs.Add(&errs.DuplicateTaskError{
+					Path:         path,
+					Line:         task.Line,
+					TaskName:     name,
+					OriginalPath: existing.SourcePath,
+					OriginalLine: existing.Line,
+				})
+				continue
+			}
+			task.Name = name
+			task.SourcePath = path
+			task.BaseDir = baseDir
+			tasks[name] = &task
+		}
+	}
+
+	if verrs.HasErrors() {
+		return verrs
+	}
+	return nil
+}
+
+func readFragment(path string) (*babfile.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &errs.ParseError{Path: path, Message: "reading fragment", Cause: err}
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil, nil
+	}
+
+	doc, err := unmarshalDocument(path, data)
+	if err != nil {
+		return nil, babfileError(path, err)
+	}
+	verrs := &errs.ValidationErrors{}
+	for i := 0; i < len(doc.Content); i += 2 {
+		key := doc.Content[i]
+		if slices.Contains(fragmentRootKeys, key.Value) {
+			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: key.Value + " is not allowed in fragments, only tasks can be defined"})
+		}
+	}
+	if verrs.HasErrors() {
+		return nil, verrs
+	}
+
+	bf, err := decodeSchema(path, doc)
+	if err != nil {
+		return nil, babfileError(path, err)
+	}
+	return bf, nil
+}
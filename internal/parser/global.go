@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/charmbracelet/log"
+)
+
+const GlobalNamespace = "global"
+
+func IncludeGlobal(result *ParseResult, path, dir string) error {
+	for name := range result.Tasks {
+		if name == GlobalNamespace || strings.HasPrefix(name, GlobalNamespace+":") {
+			log.Debug("Not including global Babfile, namespace is taken", "namespace", GlobalNamespace, "task", name)
+			return nil
+		}
+	}
+
+	global, err := Parse(path)
+	if err != nil {
+		return err
+	}
+
+	inc := babfile.Include{Babfile: path, Dir: dir}
+	if err := importTasks(GlobalNamespace, inc, filepath.Dir(result.Path), global.Path, global, result.Tasks, nil); err != nil {
+		return err
+	}
+	result.Aliases = buildAliasMap(result.Tasks)
+	return nil
+}
@@ -1,40 +1,89 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/bab-sh/bab/internal/babfile"
 	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/remote"
 	"github.com/charmbracelet/log"
 )
 
-func resolveInclude(namespace, babfilePath, baseDir string, tasks babfile.TaskMap, visited map[string]bool) error {
-	incPath := babfilePath
-	if !filepath.IsAbs(incPath) {
+func resolveInclude(namespace string, inc babfile.Include, baseDir string, tasks babfile.TaskMap, visited map[string]bool, remotes *remote.Fetcher) error {
+	incPath := inc.Babfile
+	if inc.IsRemote() {
+		checkout, err := remotes.Fetch(inc.Git, inc.Ref)
+		if errors.Is(err, remote.ErrNotCached) {
+			log.Warn("Skipping remote include, run a task or bab includes update to fetch it", "namespace", namespace, "error", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		incPath = filepath.Join(checkout, inc.RemotePath())
+	} else if !filepath.IsAbs(incPath) {
 		incPath = filepath.Join(baseDir, incPath)
 	}
 	incPath = filepath.Clean(incPath)
 
+	if inc.Optional {
+		if _, err := os.Stat(incPath); errors.Is(err, os.ErrNotExist) {
+			log.Debug("Skipping optional include", "namespace", namespace, "path", incPath)
+			return nil
+		}
+	}
+
 	log.Debug("Resolving include", "namespace", namespace, "path", incPath)
 
-	result, err := parseFile(incPath, visited)
+	result, err := parseFile(incPath, visited, remotes)
 	if err != nil {
 		return err
 	}
 
+	return importTasks(namespace, inc, baseDir, incPath, result, tasks, remotes)
+}
+
+func importTasks(namespace string, inc babfile.Include, baseDir, incPath string, result *ParseResult, tasks babfile.TaskMap, remotes *remote.Fetcher) error {
+	prefix := namespace
+	if inc.Flatten {
+		prefix = ""
+	}
+
+	var rootDir string
+	if inc.Dir != "" {
+		rootDir = inc.Dir
+		if !filepath.IsAbs(rootDir) {
+			rootDir = filepath.Join(baseDir, rootDir)
+		}
+		rootDir = filepath.Clean(rootDir)
+	}
+
 	for name, task := range result.Tasks {
-		prefixedName := namespace + ":" + name
+		prefixedName := qualify(prefix, name)
 		if tasks.Has(prefixedName) {
 			return &errs.ParseError{Path: incPath, Message: "task name collision: " + prefixedName}
 		}
 
-		taskVars := babfile.MergeVarMaps(result.GlobalVars, task.Vars)
-		taskEnv := babfile.MergeEnvMaps(result.GlobalEnv, task.Env)
+		taskVars := babfile.MergeVarMaps(result.GlobalVars, inc.Vars, task.Vars)
+		taskEnv := babfile.MergeEnvMaps(result.GlobalEnv, inc.Env, task.Env)
 
 		taskDir := task.Dir
 		if taskDir == "" {
 			taskDir = result.GlobalDir
 		}
+		if taskDir == "" && rootDir == "" && inc.IsRemote() && !remotes.InCache(baseDir) {
+			taskDir = baseDir
+		}
+
+		taskBaseDir := task.BaseDir
+		if taskBaseDir == "" || (rootDir != "" && taskBaseDir == filepath.Dir(result.Path)) {
+			taskBaseDir = rootDir
+		}
 
 		taskSilent := task.Silent
 		if taskSilent == nil {
@@ -46,33 +95,107 @@ func resolveInclude(namespace, babfilePath, baseDir string, tasks babfile.TaskMa
 			taskOutput = result.GlobalOutput
 		}
 
+		aliases := prefixAliases(task.Aliases, prefix)
+		for _, nsAlias := range inc.Aliases {
+			aliases = append(aliases, qualify(nsAlias, name))
+			for _, alias := range task.GetAllAliases() {
+				aliases = append(aliases, qualify(nsAlias, alias))
+			}
+		}
+
 		tasks[prefixedName] = &babfile.Task{
-			Name:       prefixedName,
-			Line:       task.Line,
-			DepsLine:   task.DepsLine,
-			SourcePath: task.SourcePath,
-			Desc:       task.Desc,
-			Alias:      prefixAlias(task.Alias, namespace),
-			Aliases:    prefixAliases(task.Aliases, namespace),
-			Vars:       taskVars,
-			Env:        taskEnv,
-			Silent:     taskSilent,
-			Output:     taskOutput,
-			Dir:        taskDir,
-			When:       task.When,
-			Deps:       prefixDeps(task.Deps, namespace),
-			Run:        prefixTaskRuns(task.Run, namespace),
+			Name:        prefixedName,
+			Line:        task.Line,
+			DepsLine:    task.DepsLine,
+			SourcePath:  task.SourcePath,
+			BaseDir:     taskBaseDir,
+			Local:       task.Local,
+			Desc:        task.Desc,
+			Alias:       prefixAlias(task.Alias, prefix),
+			Aliases:     aliases,
+			Vars:        taskVars,
+			Env:         taskEnv,
+			Silent:      taskSilent,
+			Output:      taskOutput,
+			Dir:         taskDir,
+			When:        task.When,
+			KillTimeout: task.KillTimeout,
+			Platforms:   task.Platforms,
+			Sources:     task.Sources,
+			Deps:        prefixDeps(task.Deps, prefix),
+			Run:         prefixTaskRuns(task.Run, prefix),
+		}
+	}
+
+	return nil
+}
+
+func resolveGlobInclude(namespace string, inc babfile.Include, baseDir string, tasks babfile.TaskMap, visited map[string]bool, remotes *remote.Fetcher) error {
+	pattern := inc.Glob
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid glob %q: %w", inc.Glob, err)
+	}
+	if len(matches) == 0 {
+		if inc.Optional {
+			log.Debug("Skipping optional include", "namespace", namespace, "glob", inc.Glob)
+			return nil
 		}
+		return fmt.Errorf("glob %q matched no babfiles", inc.Glob)
 	}
+	sort.Strings(matches)
+
+	seen := make(map[string]string, len(matches))
+	for _, match := range matches {
+		if visited[match] {
+			log.Debug("Skipping glob match that is already being parsed", "namespace", namespace, "path", match)
+			continue
+		}
+
+		name := filepath.Base(filepath.Dir(match))
+		if !taskNameRegex.MatchString(name) || strings.Contains(name, ":") {
+			return fmt.Errorf("glob match %s: directory name %q is not a valid namespace", match, name)
+		}
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("glob matches %s and %s both use namespace %q", other, match, name)
+		}
+		seen[name] = match
 
+		child := inc
+		child.Glob = ""
+		child.Babfile = match
+		child.Flatten = false
+		child.Aliases = make([]string, len(inc.Aliases))
+		for i, alias := range inc.Aliases {
+			child.Aliases[i] = qualify(alias, name)
+		}
+
+		childNamespace := qualify(namespace, name)
+		if inc.Flatten {
+			childNamespace = name
+		}
+		if err := resolveInclude(childNamespace, child, baseDir, tasks, visited, remotes); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func qualify(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
 func prefixAlias(alias, namespace string) string {
 	if alias == "" {
 		return ""
 	}
-	return namespace + ":" + alias
+	return qualify(namespace, alias)
 }
 
 func prefixAliases(aliases []string, namespace string) []string {
@@ -81,18 +204,19 @@ func prefixAliases(aliases []string, namespace string) []string {
 	}
 	prefixed := make([]string, len(aliases))
 	for i, alias := range aliases {
-		prefixed[i] = namespace + ":" + alias
+		prefixed[i] = qualify(namespace, alias)
 	}
 	return prefixed
 }
 
-func prefixDeps(deps []string, namespace string) []string {
+func prefixDeps(deps []babfile.Dep, namespace string) []babfile.Dep {
 	if len(deps) == 0 {
 		return nil
 	}
-	prefixed := make([]string, len(deps))
+	prefixed := make([]babfile.Dep, len(deps))
 	for i, dep := range deps {
-		prefixed[i] = namespace + ":" + dep
+		dep.Task = qualify(namespace, dep.Task)
+		prefixed[i] = dep
 	}
 	return prefixed
 }
@@ -106,7 +230,9 @@ func prefixTaskRuns(items []babfile.RunItem, namespace string) []babfile.RunItem
 		case babfile.TaskRun:
 			prefixed[i] = babfile.TaskRun{
 				Line:      v.Line,
-				Task:      namespace + ":" + v.Task,
+				Task:      qualify(namespace, v.Task),
+				Vars:      v.Vars,
+				Env:       v.Env,
 				Silent:    v.Silent,
 				Output:    v.Output,
 				Platforms: v.Platforms,
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+const localBabfileName = "Babfile.local.yml"
+
+type taskOverride struct {
+	name       string
+	line       int
+	keys       []string
+	task       babfile.Task
+	runAppend  []babfile.RunItem
+	depsAppend []babfile.Dep
+}
+
+func localPath(path string) string {
+	ext := filepath.Ext(path)
+	if ext != ".yml" && ext != ".yaml" {
+		return path + ".local.yml"
+	}
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+func mergeLocal(result *ParseResult) error {
+	path := localPath(result.Path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return &errs.ParseError{Path: path, Message: "reading local babfile", Cause: err}
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+	log.Debug("Merging local babfile", "path", path)
+
+	bf, overrides, err := readLocal(path, data)
+	if err != nil {
+		return err
+	}
+
+	result.GlobalVars = babfile.MergeVarMaps(result.GlobalVars, bf.Vars)
+	result.GlobalEnv = babfile.MergeEnvMaps(result.GlobalEnv, bf.Env)
+	if bf.Silent != nil {
+		result.GlobalSilent = bf.Silent
+	}
+	if bf.Output != nil {
+		result.GlobalOutput = bf.Output
+	}
+	if bf.Dir != "" {
+		result.GlobalDir = bf.Dir
+	}
+	maps.Copy(result.Profiles, bf.Profiles)
+
+	verrs := &errs.ValidationErrors{}
+	for _, name := range slices.Sorted(maps.Keys(bf.Tasks)) {
+		task := bf.Tasks[name]
+		if existing, ok := result.Tasks[name]; ok {
+			verrs.Add(&errs.DuplicateTaskError{
+				Path:         path,
+				Line:         task.Line,
+				TaskName:     name,
+				OriginalPath: existing.SourcePath,
+				OriginalLine: existing.Line,
+			})
+			continue
+		}
+		task.Name = name
+		task.SourcePath = path
+		task.Local = true
+		result.Tasks[name] = &task
+	}
+	resolveExtends(path, result.Tasks, bf.Templates, verrs)
+
+	for _, o := range overrides {
+		task, ok := result.Tasks[o.name]
+		if !ok {
+			verrs.Add(&errs.ParseError{Path: path, Line: o.line, Message: fmt.Sprintf("override %q: task not found", o.name)})
+			continue
+		}
+		applyOverride(task, o)
+	}
+
+	if verrs.HasErrors() {
+		return verrs
+	}
+	result.Aliases = buildAliasMap(result.Tasks)
+	return nil
+}
+
+func readLocal(path string, data []byte) (*babfile.Schema, []taskOverride, error) {
+	doc, err := unmarshalDocument(path, data)
+	if err != nil {
+		return nil, nil, babfileError(path, err)
+	}
+
+	verrs := &errs.ValidationErrors{}
+	var overrideNode *yaml.Node
+	for i := 0; i < len(doc.Content); i += 2 {
+		key := doc.Content[i]
+		switch key.Value {
+		case keyOverride:
+			overrideNode = doc.Content[i+1]
+			doc.Content = slices.Delete(doc.Content, i, i+2)
+			i -= 2
+		case keyIncludes:
+			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: "includes is not allowed in " + localBabfileName})
+		}
+	}
+
+	bf, err := decodeSchema(path, doc)
+	if err != nil {
+		return nil, nil, babfileError(path, err)
+	}
+
+	var overrides []taskOverride
+	if overrideNode != nil {
+		overrides = parseOverrides(path, overrideNode, verrs)
+	}
+	if verrs.HasErrors() {
+		return nil, nil, verrs
+	}
+	return bf, overrides, nil
+}
+
+func parseOverrides(path string, node *yaml.Node, verrs *errs.ValidationErrors) []taskOverride {
+	if node.Kind != yaml.MappingNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: "override must be a mapping"})
+		return nil
+	}
+
+	var overrides []taskOverride
+	for i := 0; i < len(node.Content); i += 2 {
+		nameNode := node.Content[i]
+		if o, ok := parseOverride(path, nameNode, node.Content[i+1], verrs); ok {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides
+}
+
+func parseOverride(path string, nameNode, node *yaml.Node, verrs *errs.ValidationErrors) (taskOverride, bool) {
+	name := nameNode.Value
+	o := taskOverride{name: name, line: nameNode.Line}
+	if node.Kind != yaml.MappingNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("override %q: must be a mapping", name)})
+		return o, false
+	}
+
+	fields := &yaml.Node{Kind: yaml.MappingNode, Line: node.Line}
+	hasErrors := false
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		val := node.Content[i+1]
+
+		switch key.Value {
+		case keyRunAppend:
+			items, ok := parseRunItems(path, val, name, verrs)
+			if !ok {
+				hasErrors = true
+			}
+			o.runAppend = items
+		case keyDepsAppend:
+			if !parseDeps(path, name, val, &o.depsAppend, verrs) {
+				hasErrors = true
+			}
+		case keyExtends:
+			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: fmt.Sprintf("override %q: extends is not allowed in overrides", name)})
+			hasErrors = true
+		default:
+			o.keys = append(o.keys, key.Value)
+			fields.Content = append(fields.Content, key, val)
+		}
+	}
+
+	task, ok := parseTask(path, fields, name, verrs)
+	if !ok || hasErrors {
+		return o, false
+	}
+	o.task = task
+	return o, true
+}
+
+func applyOverride(task *babfile.Task, o taskOverride) {
+	for _, key := range o.keys {
+		switch key {
+		case keyDesc:
+			task.Desc = o.task.Desc
+		case keyAlias:
+			task.Alias = o.task.Alias
+		case keyAliases:
+			task.Aliases = o.task.Aliases
+		case keyVars, keyVarsFile:
+			task.Vars = babfile.MergeVarMaps(task.Vars, o.task.Vars)
+		case keyEnv:
+			task.Env = babfile.MergeEnvMaps(task.Env, o.task.Env)
+		case keySilent:
+			task.Silent = o.task.Silent
+		case keyOutput:
+			task.Output = o.task.Output
+		case keyDir:
+			task.Dir = o.task.Dir
+		case keyWhen:
+			task.When = o.task.When
+		case keyKillTimeout:
+			task.KillTimeout = o.task.KillTimeout
+		case keyPlatforms:
+			task.Platforms = o.task.Platforms
+		case keySources:
+			task.Sources = o.task.Sources
+		case keyDeps:
+			task.Deps = o.task.Deps
+		case keyRun:
+			task.Run = o.task.Run
+		}
+	}
+	if len(o.depsAppend) > 0 {
+		task.Deps = append(slices.Clone(task.Deps), o.depsAppend...)
+	}
+	if len(o.runAppend) > 0 {
+		task.Run = append(slices.Clone(task.Run), o.runAppend...)
+	}
+	task.Local = true
+}
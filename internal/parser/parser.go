@@ -9,6 +9,7 @@ import (
 
 	"github.com/bab-sh/bab/internal/babfile"
 	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/remote"
 	"github.com/charmbracelet/log"
 )
 
@@ -19,34 +20,83 @@ type ParseResult struct {
 	GlobalSilent *bool
 	GlobalOutput *bool
 	GlobalDir    string
+	Profiles     map[string]babfile.Profile
 	Tasks        babfile.TaskMap
 	Aliases      map[string]string
+	Unfetched    []string
 }
 
 func Parse(path string) (*ParseResult, error) {
+	result, _, err := parse(path, remote.ReadOnly)
+	return result, err
+}
+
+func ParseForRun(path string) (*ParseResult, error) {
+	result, _, err := parse(path, remote.Pin)
+	return result, err
+}
+
+func UpdateIncludes(path string) (*remote.Lock, error) {
+	_, lock, err := parse(path, remote.Update)
+	return lock, err
+}
+
+func parse(path string, mode remote.Mode) (*ParseResult, *remote.Lock, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, &errs.ParseError{Path: path, Message: "path cannot be empty", Cause: errs.ErrPathEmpty}
+		return nil, nil, &errs.ParseError{Path: path, Message: "path cannot be empty", Cause: errs.ErrPathEmpty}
 	}
 
 	absPath, err := filepath.Abs(filepath.Clean(path))
 	if err != nil {
-		return nil, &errs.ParseError{Path: path, Message: "invalid path", Cause: err}
+		return nil, nil, &errs.ParseError{Path: path, Message: "invalid path", Cause: err}
 	}
 
+	lock, err := remote.LoadLock(remote.LockPath(absPath))
+	if err != nil {
+		return nil, nil, &errs.ParseError{Path: absPath, Message: "invalid lock file", Cause: err}
+	}
+	remotes := remote.NewFetcher(lock, mode)
+
 	visited := make(map[string]bool)
-	result, err := parseFile(absPath, visited)
+	result, err := parseFile(absPath, visited, remotes)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := mergeLocal(result); err != nil {
+		return nil, nil, err
+	}
+
+	if mode == remote.Update {
+		lock.Prune()
+	}
+	if mode != remote.ReadOnly {
+		if err := lock.Save(); err != nil {
+			return nil, nil, &errs.ParseError{Path: lock.Path(), Message: "writing lock file", Cause: err}
+		}
 	}
 
+	result.Unfetched = remotes.Unfetched
 	if err := validateAll(absPath, result.Tasks); err != nil {
-		return nil, err
+		return nil, nil, markUnfetched(err, result.Unfetched)
 	}
 
-	return result, nil
+	return result, lock, nil
+}
+
+func markUnfetched(err error, unfetched []string) error {
+	var verrs *errs.ValidationErrors
+	if len(unfetched) == 0 || !errors.As(err, &verrs) {
+		return err
+	}
+	for _, e := range verrs.Errors {
+		if notFound, ok := e.(*errs.TaskNotFoundError); ok {
+			notFound.Unfetched = unfetched
+		}
+	}
+	return err
 }
 
-func parseFile(absPath string, visited map[string]bool) (*ParseResult, error) {
+func parseFile(absPath string, visited map[string]bool, remotes *remote.Fetcher) (*ParseResult, error) {
 	log.Debug("Parsing babfile", "path", absPath)
 
 	if visited[absPath] {
@@ -56,34 +106,9 @@ func parseFile(absPath string, visited map[string]bool) (*ParseResult, error) {
 	visited[absPath] = true
 	defer delete(visited, absPath)
 
-	data, err := os.ReadFile(absPath)
+	bf, err := readBabfile(absPath)
 	if err != nil {
-		return nil, &errs.ParseError{Path: absPath, Message: "file not found", Cause: err}
-	}
-
-	bf, err := unmarshalBabfile(absPath, data)
-	if err != nil {
-		var verrs *errs.ValidationErrors
-		if errors.As(err, &verrs) {
-			return nil, verrs
-		}
-
-		var parseErr *errs.ParseError
-		if errors.As(err, &parseErr) {
-			return nil, parseErr
-		}
-
-		line := errs.ExtractYAMLLocation(err)
-		cleanMsg := errs.CleanYAMLError(err)
-		if cleanMsg == "" {
-			cleanMsg = err.Error()
-		}
-		return nil, &errs.ParseError{
-			Path:    absPath,
-			Line:    line,
-			Message: "invalid YAML syntax",
-			Cause:   errors.New(cleanMsg),
-		}
+		return nil, err
 	}
 
 	tasks := make(babfile.TaskMap, len(bf.Tasks))
@@ -94,12 +119,26 @@ func parseFile(absPath string, visited map[string]bool) (*ParseResult, error) {
 	}
 
 	baseDir := filepath.Dir(absPath)
+	if err := mergeFragments(baseDir, tasks); err != nil {
+		return nil, err
+	}
+
 	for namespace, inc := range bf.Includes {
-		if err := resolveInclude(namespace, inc.Babfile, baseDir, tasks, visited); err != nil {
+		resolve := resolveInclude
+		if inc.Glob != "" {
+			resolve = resolveGlobInclude
+		}
+		if err := resolve(namespace, inc, baseDir, tasks, visited, remotes); err != nil {
 			return nil, &errs.ParseError{Path: absPath, Message: "include " + namespace + " failed", Cause: err}
 		}
 	}
 
+	verrs := &errs.ValidationErrors{}
+	resolveExtends(absPath, tasks, bf.Templates, verrs)
+	if verrs.HasErrors() {
+		return nil, verrs
+	}
+
 	log.Debug("Parsed babfile", "path", absPath, "tasks", len(tasks))
 	return &ParseResult{
 		Path:         absPath,
@@ -108,11 +147,52 @@ func parseFile(absPath string, visited map[string]bool) (*ParseResult, error) {
 		GlobalSilent: bf.Silent,
 		GlobalOutput: bf.Output,
 		GlobalDir:    bf.Dir,
+		Profiles:     bf.Profiles,
 		Tasks:        tasks,
 		Aliases:      buildAliasMap(tasks),
 	}, nil
 }
 
+func readBabfile(absPath string) (*babfile.Schema, error) {
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, &errs.ParseError{Path: absPath, Message: "file not found", Cause: err}
+	}
+	return decodeBabfile(absPath, data)
+}
+
+func decodeBabfile(absPath string, data []byte) (*babfile.Schema, error) {
+	bf, err := unmarshalBabfile(absPath, data)
+	if err != nil {
+		return nil, babfileError(absPath, err)
+	}
+	return bf, nil
+}
+
+func babfileError(absPath string, err error) error {
+	var verrs *errs.ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+
+	var parseErr *errs.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr
+	}
+
+	line := errs.ExtractYAMLLocation(err)
+	cleanMsg := errs.CleanYAMLError(err)
+	if cleanMsg == "" {
+		cleanMsg = err.Error()
+	}
+	return &errs.ParseError{
+		Path:    absPath,
+		Line:    line,
+		Message: "invalid YAML syntax",
+		Cause:   errors.New(cleanMsg),
+	}
+}
+
 func buildAliasMap(tasks babfile.TaskMap) map[string]string {
 	aliases := make(map[string]string)
 	for name, task := range tasks {
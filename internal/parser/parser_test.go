@@ -2,10 +2,17 @@ package parser
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/adrg/xdg"
 	"github.com/bab-sh/bab/internal/babfile"
 	"github.com/bab-sh/bab/internal/errs"
 )
@@ -79,12 +86,12 @@ func TestParseDependencies(t *testing.T) {
 	}
 
 	buildTask := result.Tasks["build"]
-	if buildTask == nil || len(buildTask.Deps) != 1 || buildTask.Deps[0] != "clean" {
+	if buildTask == nil || len(buildTask.Deps) != 1 || buildTask.Deps[0].Task != "clean" {
 		t.Errorf("build should depend on 'clean', got %v", buildTask.Deps)
 	}
 
 	testTask := result.Tasks["test"]
-	if testTask == nil || len(testTask.Deps) != 1 || testTask.Deps[0] != buildTask.Name {
+	if testTask == nil || len(testTask.Deps) != 1 || testTask.Deps[0].Task != buildTask.Name {
 		t.Errorf("test should depend on 'build', got %v", testTask.Deps)
 	}
 }
@@ -243,9 +250,9 @@ func TestParseIncludeWithDeps(t *testing.T) {
 		t.Errorf("expected 2 dependencies, got %d: %v", len(all.Deps), all.Deps)
 	}
 
-	hasDep := func(deps []string, name string) bool {
+	hasDep := func(deps []babfile.Dep, name string) bool {
 		for _, d := range deps {
-			if d == name {
+			if d.Task == name {
 				return true
 			}
 		}
@@ -373,7 +380,7 @@ func TestParseDeepNestedInclude(t *testing.T) {
 	if firstBuild == nil {
 		t.Fatal("task 'first:build' not found")
 	}
-	if len(firstBuild.Deps) != 1 || firstBuild.Deps[0] != "first:second:compile" {
+	if len(firstBuild.Deps) != 1 || firstBuild.Deps[0].Task != "first:second:compile" {
 		t.Errorf("expected dep 'first:second:compile', got %v", firstBuild.Deps)
 	}
 
@@ -920,6 +927,21 @@ func TestParsePromptSelect(t *testing.T) {
 	}
 }
 
+func TestParsePromptRemember(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "prompt_remember.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	prompt, ok := result.Tasks["test"].Run[0].(babfile.PromptRun)
+	if !ok {
+		t.Fatal("expected PromptRun")
+	}
+	if !prompt.Remember {
+		t.Error("expected remember to be true")
+	}
+}
+
 func TestParsePromptMultiselect(t *testing.T) {
 	result, err := Parse(filepath.Join("testdata", "prompt_multiselect.yml"))
 	if err != nil {
@@ -1027,6 +1049,44 @@ func TestParsePromptNumber(t *testing.T) {
 	}
 }
 
+func TestParsePromptTextPathDate(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "prompt_text_path_date.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	task := result.Tasks["release"]
+	if task == nil {
+		t.Fatal("task 'release' not found")
+	}
+
+	wantTypes := []babfile.PromptType{babfile.PromptTypeText, babfile.PromptTypePath, babfile.PromptTypeDate, babfile.PromptTypeTime}
+	for i, want := range wantTypes {
+		prompt, ok := task.Run[i].(babfile.PromptRun)
+		if !ok {
+			t.Fatalf("run[%d]: expected PromptRun", i)
+		}
+		if prompt.Type != want {
+			t.Errorf("run[%d]: expected type %q, got %q", i, want, prompt.Type)
+		}
+	}
+
+	path := task.Run[1].(babfile.PromptRun)
+	if path.Glob != "*.sql" {
+		t.Errorf("expected glob '*.sql', got %q", path.Glob)
+	}
+	date := task.Run[2].(babfile.PromptRun)
+	if date.Default != "today" {
+		t.Errorf("expected default 'today', got %q", date.Default)
+	}
+	if date.Defer {
+		t.Error("expected date prompt not to be deferred")
+	}
+	if at := task.Run[3].(babfile.PromptRun); !at.Defer {
+		t.Error("expected time prompt to be deferred")
+	}
+}
+
 func TestParsePromptErrors(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1039,6 +1099,9 @@ func TestParsePromptErrors(t *testing.T) {
 		{"options on input", "prompt_invalid_options_on_input.yml"},
 		{"confirm on input", "prompt_invalid_confirm_on_input.yml"},
 		{"number invalid default", "prompt_invalid_number_default.yml"},
+		{"glob on input", "prompt_invalid_glob_on_input.yml"},
+		{"date invalid default", "prompt_invalid_date_default.yml"},
+		{"remember on password", "prompt_invalid_remember_password.yml"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1137,6 +1200,47 @@ func TestParseDir(t *testing.T) {
 	}
 }
 
+func TestParseKillTimeout(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "kill_timeout.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	task := result.Tasks["serve"]
+	if task == nil {
+		t.Fatal("task 'serve' not found")
+	}
+	if task.KillTimeout != 30*time.Second {
+		t.Errorf("expected task KillTimeout 30s, got %v", task.KillTimeout)
+	}
+
+	first, ok := task.Run[0].(babfile.CommandRun)
+	if !ok {
+		t.Fatal("expected CommandRun")
+	}
+	if first.KillTimeout != 0 {
+		t.Errorf("expected unset command KillTimeout, got %v", first.KillTimeout)
+	}
+
+	second, ok := task.Run[1].(babfile.CommandRun)
+	if !ok {
+		t.Fatal("expected CommandRun")
+	}
+	if second.KillTimeout != 500*time.Millisecond {
+		t.Errorf("expected command KillTimeout 500ms, got %v", second.KillTimeout)
+	}
+}
+
+func TestParseKillTimeoutInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "kill_timeout_invalid.yml"))
+	if err == nil {
+		t.Fatal("expected error for invalid kill_timeout")
+	}
+	if !strings.Contains(err.Error(), `invalid kill_timeout "soon"`) {
+		t.Errorf("expected error about kill_timeout, got: %v", err)
+	}
+}
+
 func TestParseTaskSourcePath(t *testing.T) {
 	result, err := Parse(filepath.Join("testdata", "simple.yml"))
 	if err != nil {
@@ -1706,3 +1810,687 @@ func TestParseWhen(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWhenInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "when_invalid.yml"))
+	if err == nil {
+		t.Fatal("expected error for invalid when condition")
+	}
+
+	var parseErr *errs.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %T: %v", err, err)
+	}
+	if parseErr.Line != 5 || parseErr.Column != 32 {
+		t.Errorf("expected location 5:32, got %d:%d", parseErr.Line, parseErr.Column)
+	}
+	if !strings.Contains(err.Error(), `unexpected ")"`) {
+		t.Errorf("expected error about unexpected token, got: %v", err)
+	}
+}
+
+func TestParseReservedVarNames(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "vars_reserved.yml"))
+	if err == nil {
+		t.Fatal("expected error for reserved variable names")
+	}
+
+	var verrs *errs.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	if len(verrs.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(verrs.Errors), err)
+	}
+	for _, name := range []string{`"git"`, `"bab"`} {
+		if !strings.Contains(err.Error(), name+" is reserved for built-in variables") {
+			t.Errorf("expected reserved error for %s, got: %v", name, err)
+		}
+	}
+}
+
+func TestParseStructuredVars(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "vars_structured.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	services, ok := result.GlobalVars["services"].([]any)
+	if !ok || len(services) != 2 || services[0] != "api" {
+		t.Errorf("expected services list, got %#v", result.GlobalVars["services"])
+	}
+	db, ok := result.GlobalVars["db"].(map[string]any)
+	if !ok || db["host"] != "localhost" || db["port"] != "5432" {
+		t.Errorf("expected db map, got %#v", result.GlobalVars["db"])
+	}
+
+	run := result.Tasks["deploy"].Run
+	first, ok := run[0].(babfile.CommandRun)
+	if !ok || first.For != "${{ services }}" {
+		t.Errorf("expected for reference, got %#v", run[0])
+	}
+	second, ok := run[1].(babfile.CommandRun)
+	if !ok {
+		t.Fatal("expected CommandRun")
+	}
+	if items, ok := second.For.([]any); !ok || len(items) != 2 {
+		t.Errorf("expected inline for list, got %#v", second.For)
+	}
+}
+
+func TestParseForInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "for_invalid.yml"))
+	if err == nil {
+		t.Fatal("expected error for 'for' on a log item")
+	}
+	if !strings.Contains(err.Error(), "'for' is only allowed on 'cmd' items") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseTaskPlatforms(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "task_platforms.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	notarize := result.Tasks["notarize"]
+	if len(notarize.Platforms) != 1 || notarize.Platforms[0] != babfile.PlatformDarwin {
+		t.Errorf("expected darwin task platform, got %v", notarize.Platforms)
+	}
+
+	run := result.Tasks["build"].Run
+	arm, ok := run[0].(babfile.CommandRun)
+	if !ok || len(arm.Platforms) != 2 || arm.Platforms[1] != "*/arm64" {
+		t.Errorf("expected arch platforms, got %#v", run[0])
+	}
+	if !arm.ShouldRunOnPlatform("darwin/arm64") || arm.ShouldRunOnPlatform("linux/amd64") {
+		t.Error("expected arm64 command to run on arm64 only")
+	}
+	notWindows, ok := run[1].(babfile.CommandRun)
+	if !ok || notWindows.ShouldRunOnPlatform("windows/amd64") {
+		t.Errorf("expected command excluded on windows, got %#v", run[1])
+	}
+}
+
+func TestParseIncludeInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "include_invalid.yml"))
+	if err == nil {
+		t.Fatal("expected error for invalid includes")
+	}
+
+	for _, want := range []string{
+		`include "both": only one of 'babfile', 'git' and 'glob' can be set`,
+		`include "none": requires 'babfile', 'git' or 'glob'`,
+		`include "rooted": 'dir' cannot be combined with 'glob'`,
+		`include "escape": path "../Babfile.yml" must be relative to the repository root`,
+		`include "flat": 'aliases' cannot be combined with 'flatten'`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestParseRemoteInclude(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	xdg.Reload()
+
+	repo := t.TempDir()
+	shared := "tasks:\n  lint:\n    run:\n      - cmd: golangci-lint run\n"
+	if err := os.MkdirAll(filepath.Join(repo, "ci"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "ci", "Babfile.yml"), []byte(shared), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=bab", "-c", "user.email=bab@example.com", "commit", "-q", "-m", "init"},
+		{"tag", "v1.0.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	project := t.TempDir()
+	babfilePath := filepath.Join(project, "Babfile.yml")
+	content := "includes:\n  shared:\n    git: file://" + filepath.ToSlash(repo) + "\n    ref: v1.0.0\n    path: ci/Babfile.yml\n\ntasks:\n  build:\n    run:\n      - cmd: echo build\n"
+	if err := os.WriteFile(babfilePath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Parse(babfilePath)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if result.Tasks["shared:lint"] != nil {
+		t.Error("expected Parse() to skip an include that was never fetched")
+	}
+	if url := "file://" + filepath.ToSlash(repo); !slices.Equal(result.Unfetched, []string{url}) {
+		t.Errorf("expected unfetched %s, got %v", url, result.Unfetched)
+	}
+	depPath := filepath.Join(project, "deps.yml")
+	depContent := strings.Replace(content, "    run:\n      - cmd: echo build\n", "    deps: [shared:lint]\n", 1)
+	if err := os.WriteFile(depPath, []byte(depContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(depPath); err == nil || !strings.Contains(err.Error(), `task "shared:lint" not found (remote include file://`) {
+		t.Errorf("expected not found error to name the unfetched include, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "Babfile.lock")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected Parse() not to write Babfile.lock, got %v", err)
+	}
+
+	if _, err := ParseForRun(babfilePath); err != nil {
+		t.Fatalf("ParseForRun() error: %v", err)
+	}
+	result, err = Parse(babfilePath)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	lint := result.Tasks["shared:lint"]
+	if lint == nil {
+		t.Fatal("expected shared:lint task")
+	}
+	if lint.Dir != project {
+		t.Errorf("expected remote task to run in %s, got %q", project, lint.Dir)
+	}
+
+	lock, err := os.ReadFile(filepath.Join(project, "Babfile.lock"))
+	if err != nil {
+		t.Fatalf("expected Babfile.lock: %v", err)
+	}
+	if !strings.Contains(string(lock), "ref: v1.0.0") || !strings.Contains(string(lock), "commit: ") {
+		t.Errorf("unexpected lock file:\n%s", lock)
+	}
+}
+
+func TestParseIncludeOptions(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "includes", "options_main.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	task := result.Tasks["sub:no-overrides"]
+	if task == nil {
+		t.Fatal("task 'sub:no-overrides' not found")
+	}
+	if task.Vars["sub_version"] != "9.0" {
+		t.Errorf("expected include var to override global, got %v", task.Vars["sub_version"])
+	}
+	if task.Env["SUB_ENV"] != "from-include" {
+		t.Errorf("expected include env to override global, got %q", task.Env["SUB_ENV"])
+	}
+	wantRoot, _ := filepath.Abs(filepath.Join("testdata", "includes", "web"))
+	if task.BaseDir != wantRoot || task.Root() != wantRoot {
+		t.Errorf("expected base dir %q, got %q", wantRoot, task.BaseDir)
+	}
+
+	task = result.Tasks["sub:with-overrides"]
+	if task.Vars["sub_version"] != "3.0" || task.Env["SUB_ENV"] != "from-task" {
+		t.Errorf("expected task vars and env to win, got %v %v", task.Vars["sub_version"], task.Env["SUB_ENV"])
+	}
+
+	if result.Aliases["s:no-overrides"] != "sub:no-overrides" {
+		t.Errorf("expected namespace alias s:no-overrides, got %v", result.Aliases)
+	}
+
+	if !result.Tasks.Has("build") || !result.Tasks.Has("test") || result.Tasks.Has("flat:build") {
+		t.Errorf("expected flattened tasks without prefix, got %v", result.Tasks.Names())
+	}
+}
+
+func TestParseIncludeFlattenCollision(t *testing.T) {
+	dir := t.TempDir()
+	sub := "tasks:\n  build:\n    run:\n      - cmd: echo sub\n"
+	main := "includes:\n  sub:\n    babfile: ./sub/Babfile.yml\n    flatten: true\ntasks:\n  build:\n    run:\n      - cmd: echo main\n"
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "Babfile.yml"), []byte(sub), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Parse(filepath.Join(dir, "Babfile.yml"))
+	if err == nil || !strings.Contains(err.Error(), "task name collision: build") {
+		t.Errorf("expected collision error, got %v", err)
+	}
+}
+
+func TestParseIncludeMissingNotOptional(t *testing.T) {
+	dir := t.TempDir()
+	main := "includes:\n  local:\n    babfile: ./Babfile.local.yml\ntasks:\n  build:\n    run:\n      - cmd: echo main\n"
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Parse(filepath.Join(dir, "Babfile.yml")); err == nil {
+		t.Error("expected error for missing include without optional")
+	}
+}
+
+func TestParseGlobInclude(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "includes", "glob_main.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	api := result.Tasks["svc:api:test"]
+	if api == nil {
+		t.Fatalf("expected svc:api:test, got %v", result.Tasks.Names())
+	}
+	wantSource, _ := filepath.Abs(filepath.Join("testdata", "includes", "services", "api", "Babfile.yml"))
+	if api.SourcePath != wantSource {
+		t.Errorf("expected SourcePath %q, got %q", wantSource, api.SourcePath)
+	}
+
+	web := result.Tasks["svc:web:test"]
+	if web == nil || web.Dir != "./src" || web.BaseDir != "" {
+		t.Errorf("expected svc:web:test to keep its own dir, got %#v", web)
+	}
+
+	if result.Aliases["s:web:test"] != "svc:web:test" {
+		t.Errorf("expected alias s:web:test, got %v", result.Aliases)
+	}
+}
+
+func TestParseGlobIncludeNoMatches(t *testing.T) {
+	dir := t.TempDir()
+	main := "includes:\n  svc:\n    glob: services/*/Babfile.yml\ntasks:\n  build:\n    run:\n      - cmd: echo main\n"
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Parse(filepath.Join(dir, "Babfile.yml"))
+	if err == nil || !strings.Contains(err.Error(), "matched no babfiles") {
+		t.Errorf("expected no matches error, got %v", err)
+	}
+}
+
+func TestParseTaskSources(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "task_sources.yml"))
+	if err == nil {
+		t.Fatal("expected error for invalid sources")
+	}
+	for _, want := range []string{
+		`task "lint": sources must be a list of glob patterns`,
+		`task "vet": sources[0] cannot be empty`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	result, err := Parse(filepath.Join("testdata", "includes", "sources_main.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if sources := result.Tasks["svc:test"].Sources; !slices.Equal(sources, []string{"src/**/*.go", "go.mod"}) {
+		t.Errorf("expected included task sources, got %v", sources)
+	}
+}
+
+func TestIncludeGlobal(t *testing.T) {
+	globalPath := filepath.Join("testdata", "global", "Babfile.yml")
+	dir := t.TempDir()
+
+	result, err := Parse(filepath.Join("testdata", "simple.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if err := IncludeGlobal(result, globalPath, dir); err != nil {
+		t.Fatalf("IncludeGlobal() error: %v", err)
+	}
+	greet := result.Tasks["global:greet"]
+	if greet == nil {
+		t.Fatalf("expected global:greet, got %v", result.Tasks.Names())
+	}
+	if greet.BaseDir != dir {
+		t.Errorf("expected global task to run in %s, got %s", dir, greet.BaseDir)
+	}
+	if greet.Vars["greeting"] != "hello" {
+		t.Errorf("expected global vars on the task, got %v", greet.Vars)
+	}
+	if result.Aliases["global:hi"] != "global:greet" {
+		t.Errorf("expected namespaced global alias, got %v", result.Aliases)
+	}
+
+	taken, err := Parse(filepath.Join("testdata", "global", "taken.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if err := IncludeGlobal(taken, globalPath, dir); err != nil {
+		t.Fatalf("IncludeGlobal() error: %v", err)
+	}
+	if taken.Tasks.Has("global:greet") {
+		t.Error("expected global Babfile to be skipped when the namespace is taken")
+	}
+}
+
+func TestParseFragments(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "fragments", "Babfile.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if names := slices.Sorted(maps.Keys(result.Tasks)); !slices.Equal(names, []string{"build", "setup", "test"}) {
+		t.Fatalf("expected tasks from Babfile and fragments, got %v", names)
+	}
+
+	root, _ := filepath.Abs(filepath.Join("testdata", "fragments"))
+	for name, source := range map[string]string{
+		"setup": filepath.Join(root, "Babfile.yml"),
+		"build": filepath.Join(root, ".bab", "build.yml"),
+		"test":  filepath.Join(root, "Babfile.d", "test.yaml"),
+	} {
+		task := result.Tasks[name]
+		if task.SourcePath != source {
+			t.Errorf("task %q: expected SourcePath %s, got %s", name, source, task.SourcePath)
+		}
+		if task.Root() != root {
+			t.Errorf("task %q: expected root %s, got %s", name, root, task.Root())
+		}
+	}
+	if result.Tasks["build"].Line != 2 {
+		t.Errorf("expected fragment line 2, got %d", result.Tasks["build"].Line)
+	}
+}
+
+func TestParseFragmentsDuplicate(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "fragments_duplicate", "Babfile.yml"))
+	var dupErr *errs.DuplicateTaskError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateTaskError, got %v", err)
+	}
+	if dupErr.TaskName != "build" || dupErr.Line != 6 || dupErr.OriginalLine != 2 {
+		t.Errorf("unexpected duplicate error: %+v", dupErr)
+	}
+	if filepath.Base(dupErr.Path) != "build.yml" || filepath.Base(dupErr.OriginalPath) != "Babfile.yml" {
+		t.Errorf("expected fragment and Babfile paths, got %s and %s", dupErr.Path, dupErr.OriginalPath)
+	}
+	if !strings.Contains(err.Error(), "first defined at ") || !strings.Contains(err.Error(), "Babfile.yml:2") {
+		t.Errorf("expected error to point at the original file, got: %v", err)
+	}
+}
+
+func TestParseFragmentsInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "fragments_invalid", "Babfile.yml"))
+	if err == nil || !strings.Contains(err.Error(), "vars is not allowed in fragments") {
+		t.Fatalf("expected fragment key error, got %v", err)
+	}
+	if path, line := errs.Locate(err); filepath.Base(path) != "vars.yml" || line != 1 {
+		t.Errorf("expected error at vars.yml:1, got %s:%d", path, line)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".bab"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte("tasks: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".bab", "test.yml"), []byte("tasks:\n  test:\n    deps: [missing]\n    run:\n      - cmd: echo test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Parse(filepath.Join(dir, "Babfile.yml"))
+	var notFound *errs.TaskNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected TaskNotFoundError, got %v", err)
+	}
+	if notFound.Path != filepath.Join(dir, ".bab", "test.yml") {
+		t.Errorf("expected error in fragment, got %s", notFound.Path)
+	}
+}
+
+func TestParseLocal(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "local", "Babfile.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if result.GlobalVars["port"] != "8080" || result.GlobalVars["name"] != "app" {
+		t.Errorf("expected local vars merged over the Babfile, got %v", result.GlobalVars)
+	}
+	if result.GlobalEnv["MODE"] != "local" {
+		t.Errorf("expected local env, got %v", result.GlobalEnv)
+	}
+
+	scratch := result.Tasks["scratch"]
+	if scratch == nil || !scratch.Local || filepath.Base(scratch.SourcePath) != "Babfile.local.yml" {
+		t.Fatalf("expected local task, got %+v", scratch)
+	}
+
+	serve := result.Tasks["serve"]
+	if !serve.Local || serve.Desc != "Start the server on my machine" {
+		t.Errorf("expected overridden desc, got %q", serve.Desc)
+	}
+	if serve.Vars["host"] != "localhost" || serve.Vars["debug"] != "true" {
+		t.Errorf("expected merged task vars, got %v", serve.Vars)
+	}
+	if len(serve.Run) != 1 || serve.Run[0].(babfile.CommandRun).Cmd != "echo serving locally" {
+		t.Errorf("expected replaced run, got %v", serve.Run)
+	}
+
+	build := result.Tasks["build"]
+	if !slices.Equal(babfile.DepNames(build.Deps), []string{"setup", "scratch"}) {
+		t.Errorf("expected appended deps, got %v", build.Deps)
+	}
+	if len(build.Run) != 2 || build.Run[1].(babfile.CommandRun).Cmd != "echo notify" {
+		t.Errorf("expected appended run, got %v", build.Run)
+	}
+	if build.Desc != "Build the application" {
+		t.Errorf("expected untouched desc, got %q", build.Desc)
+	}
+	if result.Tasks["setup"].Local {
+		t.Error("expected setup not to be marked local")
+	}
+}
+
+func TestParseLocalInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "local_invalid", "Babfile.yml"))
+	if err == nil || !strings.Contains(err.Error(), "includes is not allowed in Babfile.local.yml") {
+		t.Errorf("expected includes error, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte("tasks:\n  build:\n    run:\n      - cmd: echo build\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local := "tasks:\n  build:\n    run:\n      - cmd: echo again\noverride:\n  missing:\n    desc: Not there\n"
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.local.yml"), []byte(local), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Parse(filepath.Join(dir, "Babfile.yml"))
+	for _, want := range []string{
+		`Babfile.local.yml:2: duplicate task "build" (first defined at `,
+		`override "missing": task not found`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	_, err = Parse(filepath.Join("testdata", "local_override.yml"))
+	if err == nil || !strings.Contains(err.Error(), "override is only allowed in Babfile.local.yml") {
+		t.Errorf("expected override error in main Babfile, got %v", err)
+	}
+}
+
+func TestParseProfiles(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "profiles.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	staging, ok := result.Profiles["staging"]
+	if !ok {
+		t.Fatalf("expected staging profile, got %v", result.Profiles)
+	}
+	if staging.Desc != "Staging cluster" || staging.Vars["stage"] != "staging" || staging.Env["REGION"] != "us-east-1" {
+		t.Errorf("unexpected staging profile: %+v", staging)
+	}
+	if !slices.Equal(staging.Dotenv, []string{".env.staging"}) {
+		t.Errorf("expected dotenv files, got %v", staging.Dotenv)
+	}
+	if !result.Profiles["prod"].Confirm {
+		t.Error("expected prod profile to require confirmation")
+	}
+
+	_, err = Parse(filepath.Join("testdata", "profiles_invalid.yml"))
+	for _, want := range []string{
+		`profiles_invalid.yml:2: invalid profile name "bad name"`,
+		`profiles_invalid.yml:6: profile "staging": dotenv must be a list of file paths`,
+		`profiles_invalid.yml:8: profile "prod": confirm must be a boolean`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestParseVarsFile(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "vars_file", "Babfile.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	for name, want := range map[string]any{
+		"name":       "web",
+		"version":    "1.4.2",
+		"workspaces": []any{"api", "web"},
+		"private":    "true",
+		"node":       ">=20.11",
+		"region":     "eu-west-1",
+		"debug":      "false",
+		"port":       "9000",
+	} {
+		if got := result.GlobalVars[name]; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("GlobalVars[%q] = %v, want %v", name, got, want)
+		}
+	}
+	if _, ok := result.GlobalVars["lint-staged"]; ok {
+		t.Error("expected keys that aren't variable names to be skipped")
+	}
+	if dev, _ := result.GlobalVars["devDependencies"].(map[string]any); dev["@types/node"] != "^20.14.2" {
+		t.Errorf("expected nested keys to be kept, got %v", result.GlobalVars["devDependencies"])
+	}
+
+	release := result.Tasks["release"].Vars
+	if release["name"] != "override" || release["settings"] == nil {
+		t.Errorf("expected task vars_file merged below task vars, got %v", release)
+	}
+}
+
+func TestParseVarsFileInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "vars_file_invalid", "Babfile.yml"))
+	if err == nil {
+		t.Fatal("expected error for invalid vars files")
+	}
+	for _, want := range []string{
+		`Babfile.yml:1: vars_file "missing.yml": file not found`,
+		`Babfile.yml:5: vars_file "config.yml#nope": key "nope" not found`,
+		`Babfile.yml:5: vars_file "config.yml#app.bad-name": key "bad-name" can't be used as a variable name`,
+		`Babfile.yml:5: vars_file "config.toml": unsupported file type`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestParseTaskArgs(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "task_args.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	pkg := result.Tasks["package"]
+	if len(pkg.Deps) != 2 || pkg.Deps[0].Task != "build" || pkg.Deps[0].Vars != nil {
+		t.Fatalf("expected plain build dep first, got %+v", pkg.Deps)
+	}
+	dep := pkg.Deps[1]
+	if dep.Task != "build" || dep.Vars["target"] != "arm64" || dep.Env["CGO_ENABLED"] != "0" {
+		t.Errorf("unexpected dep: %+v", dep)
+	}
+	run, ok := pkg.Run[0].(babfile.TaskRun)
+	if !ok || run.Vars["target"] != "riscv64" || run.Env["GOOS"] != "linux" {
+		t.Errorf("unexpected task run: %+v", pkg.Run[0])
+	}
+
+	_, err = Parse(filepath.Join("testdata", "task_args_invalid.yml"))
+	for _, want := range []string{
+		`task_args_invalid.yml:4: task "build": run[0]: 'vars' is only allowed on 'task' items`,
+		`task_args_invalid.yml:10:9: task "package": deps[0]: unknown key "silent", must be one of: task, vars, env`,
+		`task_args_invalid.yml:11:9: task "package": deps[1]: dep must have 'task'`,
+		`task_args_invalid.yml:13:9: task "package": deps[2]: dep must be a task name or a mapping`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestParseExtends(t *testing.T) {
+	result, err := Parse(filepath.Join("testdata", "extends.yml"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if result.Tasks.Has("service") {
+		t.Error("templates should not be runnable tasks")
+	}
+
+	api := result.Tasks["api"]
+	if api.Vars["name"] != "api" || api.Vars["port"] != "9000" || api.Env["DOCKER_BUILDKIT"] != "1" {
+		t.Errorf("unexpected api vars/env: %v %v", api.Vars, api.Env)
+	}
+	if api.Dir != "services/${{ name }}" || api.Silent == nil || !*api.Silent {
+		t.Errorf("expected inherited dir and silent, got %q %v", api.Dir, api.Silent)
+	}
+	if !slices.Equal(babfile.DepNames(api.Deps), []string{"setup"}) || len(api.Run) != 1 {
+		t.Errorf("expected inherited deps and run, got %v %v", api.Deps, api.Run)
+	}
+
+	web := result.Tasks["web"]
+	if len(web.Run) != 2 || web.Run[1].(babfile.CommandRun).Cmd != "docker push ${{ registry }}/web" {
+		t.Errorf("expected appended run item, got %v", web.Run)
+	}
+
+	dev := result.Tasks["web:dev"]
+	if dev.Vars["name"] != "web" || dev.Vars["port"] != "8080" {
+		t.Errorf("expected vars inherited through web, got %v", dev.Vars)
+	}
+	if dev.Silent == nil || *dev.Silent || len(dev.Deps) != 0 {
+		t.Errorf("expected overridden silent and deps, got %v %v", dev.Silent, dev.Deps)
+	}
+	if len(dev.Run) != 1 || dev.Run[0].(babfile.CommandRun).Cmd != "npm run dev" {
+		t.Errorf("expected replaced run, got %v", dev.Run)
+	}
+}
+
+func TestParseExtendsInvalid(t *testing.T) {
+	_, err := Parse(filepath.Join("testdata", "extends_invalid.yml"))
+	if !errors.Is(err, errs.ErrCircularDep) {
+		t.Errorf("expected errs.ErrCircularDep, got: %v", err)
+	}
+	for _, want := range []string{
+		`extends_invalid.yml:2: template "build" conflicts with a task of the same name`,
+		`extends_invalid.yml:6: circular extends: loop-a → loop-b → loop-a`,
+		`extends_invalid.yml:17: task "orphan": extends unknown task or template "missing"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	_, err = Parse(filepath.Join("testdata", "extends_run_append.yml"))
+	if want := `task "build": run_append requires extends`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got: %v", want, err)
+	}
+}
@@ -1,12 +1,19 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/condition"
 	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/interpolate"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,17 +24,26 @@ const (
 	keyDeps        = "deps"
 	keyDesc        = "desc"
 	keyDir         = "dir"
+	keyDotenv      = "dotenv"
 	keyEnv         = "env"
+	keyExtends     = "extends"
 	keyIncludes    = "includes"
 	keyLevel       = "level"
 	keyLog         = "log"
 	keyOutput      = "output"
+	keyOverride    = "override"
+	keyRunAppend   = "run_append"
+	keyDepsAppend  = "deps_append"
 	keyPlatforms   = "platforms"
+	keyProfiles    = "profiles"
 	keyRun         = "run"
 	keySilent      = "silent"
+	keySources     = "sources"
 	keyTask        = "task"
 	keyTasks       = "tasks"
+	keyTemplates   = "templates"
 	keyVars        = "vars"
+	keyVarsFile    = "vars_file"
 	keyWhen        = "when"
 	keyPrompt      = "prompt"
 	keyType        = "type"
@@ -45,6 +61,11 @@ const (
 	keyLimit       = "limit"
 	keyColor       = "color"
 	keyLabel       = "label"
+	keyKillTimeout = "kill_timeout"
+	keyFor         = "for"
+	keyGlob        = "glob"
+	keyDefer       = "defer"
+	keyRemember    = "remember"
 )
 
 type promptFields struct {
@@ -54,14 +75,21 @@ type promptFields struct {
 	dflt        string
 	placeholder string
 	validate    string
+	glob        string
 	options     []string
 	defaults    []string
 	min         *int
 	max         *int
 	confirm     *bool
+	deferred    *bool
+	remember    *bool
 }
 
-var varNameRegex = regexp.MustCompile(babfile.VarNamePattern)
+var (
+	varNameRegex     = regexp.MustCompile(babfile.VarNamePattern)
+	taskNameRegex    = regexp.MustCompile(babfile.TaskNamePattern)
+	profileNameRegex = regexp.MustCompile(babfile.ProfileNamePattern)
+)
 
 func parseEnvMap(path string, node *yaml.Node, env *map[string]string, verrs *errs.ValidationErrors) bool {
 	if node.Kind != yaml.MappingNode {
@@ -105,22 +133,89 @@ func parseVarMap(path string, node *yaml.Node, vars *babfile.VarMap, verrs *errs
 		keyNode := node.Content[i]
 		valNode := node.Content[i+1]
 
-		if valNode.Kind != yaml.ScalarNode {
+		if !varNameRegex.MatchString(keyNode.Value) {
 			verrs.Add(&errs.ParseError{
 				Path:    path,
-				Line:    valNode.Line,
-				Message: fmt.Sprintf("vars value for %q must be a string", keyNode.Value),
+				Line:    keyNode.Line,
+				Message: fmt.Sprintf("invalid vars name %q, must match pattern %s", keyNode.Value, babfile.VarNamePattern),
 			})
 			hasErrors = true
 			continue
 		}
 
-		(*vars)[keyNode.Value] = valNode.Value
+		if interpolate.IsReserved(keyNode.Value) {
+			verrs.Add(&errs.ParseError{
+				Path:    path,
+				Line:    keyNode.Line,
+				Message: fmt.Sprintf("vars name %q is reserved for built-in variables", keyNode.Value),
+			})
+			hasErrors = true
+			continue
+		}
+
+		value, ok := parseVarValue(path, keyNode.Value, valNode, verrs)
+		if !ok {
+			hasErrors = true
+			continue
+		}
+		(*vars)[keyNode.Value] = value
 	}
 
 	return !hasErrors
 }
 
+func parseVarValue(path, name string, node *yaml.Node, verrs *errs.ValidationErrors) (any, bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, true
+	case yaml.AliasNode:
+		return parseVarValue(path, name, node.Alias, verrs)
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(node.Content))
+		ok := true
+		for i, item := range node.Content {
+			v, itemOK := parseVarValue(path, fmt.Sprintf("%s[%d]", name, i), item, verrs)
+			ok = ok && itemOK
+			list = append(list, v)
+		}
+		return list, ok
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		ok := true
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			v, itemOK := parseVarValue(path, name+"."+key, node.Content[i+1], verrs)
+			ok = ok && itemOK
+			m[key] = v
+		}
+		return m, ok
+	default:
+		verrs.Add(&errs.ParseError{
+			Path:    path,
+			Line:    node.Line,
+			Message: fmt.Sprintf("vars value for %q must be a string, list or map", name),
+		})
+		return nil, false
+	}
+}
+
+func parseFor(path, prefix string, node *yaml.Node, target *any, verrs *errs.ValidationErrors) bool {
+	switch {
+	case node.Kind == yaml.SequenceNode:
+		value, ok := parseVarValue(path, "for", node, verrs)
+		if ok {
+			*target = value
+		}
+		return ok
+	case node.Kind == yaml.ScalarNode && interpolate.ContainsVarRef(node.Value):
+		*target = node.Value
+		return true
+	default:
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: for must be a list or a ${{ var }} reference", prefix)})
+		return false
+	}
+}
+
 func parseBool(path string, node *yaml.Node, target **bool, verrs *errs.ValidationErrors) bool {
 	if node.Kind != yaml.ScalarNode {
 		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: "expected boolean value"})
@@ -137,7 +232,71 @@ func parseBool(path string, node *yaml.Node, target **bool, verrs *errs.Validati
 	return true
 }
 
+func parseKillTimeout(path, prefix string, node *yaml.Node, target *time.Duration, verrs *errs.ValidationErrors) bool {
+	if node.Kind != yaml.ScalarNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: kill_timeout must be a duration string", prefix)})
+		return false
+	}
+
+	d, err := time.ParseDuration(node.Value)
+	if err != nil {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: invalid kill_timeout %q, expected a duration like '10s'", prefix, node.Value)})
+		return false
+	}
+	if d <= 0 {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: kill_timeout must be greater than 0", prefix)})
+		return false
+	}
+
+	*target = d
+	return true
+}
+
+func parseWhen(path, prefix string, node *yaml.Node, target *string, verrs *errs.ValidationErrors) bool {
+	if node.Kind != yaml.ScalarNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: when must be a string", prefix)})
+		return false
+	}
+
+	if _, err := condition.Parse(node.Value); err != nil {
+		var condErr *errs.ConditionError
+		if !errors.As(err, &condErr) {
+			verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: invalid when condition", prefix), Cause: err})
+			return false
+		}
+		verrs.Add(&errs.ParseError{
+			Path:    path,
+			Line:    node.Line,
+			Column:  conditionColumn(node, condErr.Pos),
+			Message: fmt.Sprintf("%s: invalid when condition %q: %s", prefix, node.Value, condErr.Message),
+		})
+		return false
+	}
+
+	*target = node.Value
+	return true
+}
+
+func conditionColumn(node *yaml.Node, pos int) int {
+	switch node.Style {
+	case 0:
+		return node.Column + pos
+	case yaml.SingleQuotedStyle, yaml.DoubleQuotedStyle:
+		return node.Column + pos + 1
+	default:
+		return 0
+	}
+}
+
 func unmarshalBabfile(path string, data []byte) (*babfile.Schema, error) {
+	doc, err := unmarshalDocument(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSchema(path, doc)
+}
+
+func unmarshalDocument(path string, data []byte) (*yaml.Node, error) {
 	var root yaml.Node
 	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, err
@@ -151,15 +310,21 @@ func unmarshalBabfile(path string, data []byte) (*babfile.Schema, error) {
 	if doc.Kind != yaml.MappingNode {
 		return nil, &errs.ParseError{Path: path, Line: doc.Line, Message: "expected mapping at root"}
 	}
+	return doc, nil
+}
 
+func decodeSchema(path string, doc *yaml.Node) (*babfile.Schema, error) {
 	schema := &babfile.Schema{
-		Vars:     make(babfile.VarMap),
-		Env:      make(map[string]string),
-		Tasks:    make(map[string]babfile.Task),
-		Includes: make(map[string]babfile.Include),
+		Vars:      make(babfile.VarMap),
+		Env:       make(map[string]string),
+		Tasks:     make(map[string]babfile.Task),
+		Includes:  make(map[string]babfile.Include),
+		Profiles:  make(map[string]babfile.Profile),
+		Templates: make(map[string]babfile.Task),
 	}
 
 	verrs := &errs.ValidationErrors{}
+	var fileVars babfile.VarMap
 
 	for i := 0; i < len(doc.Content); i += 2 {
 		key := doc.Content[i]
@@ -168,6 +333,8 @@ func unmarshalBabfile(path string, data []byte) (*babfile.Schema, error) {
 		switch key.Value {
 		case keyVars:
 			parseVarMap(path, val, &schema.Vars, verrs)
+		case keyVarsFile:
+			fileVars, _ = parseVarsFile(path, val, verrs)
 		case keyEnv:
 			parseEnvMap(path, val, &schema.Env, verrs)
 		case keySilent:
@@ -181,9 +348,15 @@ func unmarshalBabfile(path string, data []byte) (*babfile.Schema, error) {
 				schema.Dir = val.Value
 			}
 		case keyTasks:
-			parseTasks(path, val, schema, verrs)
+			parseTasks(path, keyTasks, val, schema.Tasks, verrs)
+		case keyTemplates:
+			parseTasks(path, keyTemplates, val, schema.Templates, verrs)
 		case keyIncludes:
 			parseIncludes(path, val, schema, verrs)
+		case keyProfiles:
+			parseProfiles(path, val, schema, verrs)
+		case keyOverride:
+			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: "override is only allowed in " + localBabfileName})
 		}
 	}
 
@@ -191,12 +364,15 @@ func unmarshalBabfile(path string, data []byte) (*babfile.Schema, error) {
 		return nil, verrs
 	}
 
+	if fileVars != nil {
+		schema.Vars = babfile.MergeVarMaps(fileVars, schema.Vars)
+	}
 	return schema, nil
 }
 
-func parseTasks(path string, node *yaml.Node, schema *babfile.Schema, verrs *errs.ValidationErrors) {
+func parseTasks(path, section string, node *yaml.Node, out map[string]babfile.Task, verrs *errs.ValidationErrors) {
 	if node.Kind != yaml.MappingNode {
-		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: "tasks must be a mapping"})
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: section + " must be a mapping"})
 		return
 	}
 
@@ -223,7 +399,7 @@ func parseTasks(path string, node *yaml.Node, schema *babfile.Schema, verrs *err
 			continue
 		}
 		task.Line = nameNode.Line
-		schema.Tasks[name] = task
+		out[name] = task
 	}
 }
 
@@ -235,6 +411,7 @@ func parseTask(path string, node *yaml.Node, taskName string, verrs *errs.Valida
 
 	task := babfile.Task{}
 	hasErrors := false
+	var fileVars babfile.VarMap
 
 	for i := 0; i < len(node.Content); i += 2 {
 		key := node.Content[i]
@@ -243,6 +420,14 @@ func parseTask(path string, node *yaml.Node, taskName string, verrs *errs.Valida
 		switch key.Value {
 		case keyDesc:
 			task.Desc = val.Value
+		case keyExtends:
+			if val.Kind != yaml.ScalarNode || val.Value == "" {
+				verrs.Add(&errs.ParseError{Path: path, Line: val.Line, Message: fmt.Sprintf("task %q: extends must be a task or template name", taskName)})
+				hasErrors = true
+			} else {
+				task.Extends = val.Value
+				task.ExtendsLine = key.Line
+			}
 		case keyAlias:
 			task.Alias = val.Value
 		case keyAliases:
@@ -254,6 +439,11 @@ func parseTask(path string, node *yaml.Node, taskName string, verrs *errs.Valida
 			if !parseVarMap(path, val, &task.Vars, verrs) {
 				hasErrors = true
 			}
+		case keyVarsFile:
+			var ok bool
+			if fileVars, ok = parseVarsFile(path, val, verrs); !ok {
+				hasErrors = true
+			}
 		case keyEnv:
 			if !parseEnvMap(path, val, &task.Env, verrs) {
 				hasErrors = true
@@ -274,11 +464,26 @@ func parseTask(path string, node *yaml.Node, taskName string, verrs *errs.Valida
 				task.Dir = val.Value
 			}
 		case keyWhen:
-			task.When = val.Value
+			if !parseWhen(path, fmt.Sprintf("task %q", taskName), val, &task.When, verrs) {
+				hasErrors = true
+			}
+		case keyKillTimeout:
+			if !parseKillTimeout(path, fmt.Sprintf("task %q", taskName), val, &task.KillTimeout, verrs) {
+				hasErrors = true
+			}
+		case keyPlatforms:
+			var ok bool
+			task.Platforms, ok = parsePlatforms(path, key.Line, fmt.Sprintf("task %q", taskName), val, verrs)
+			if !ok {
+				hasErrors = true
+			}
+		case keySources:
+			if !parseSources(path, taskName, val, &task.Sources, verrs) {
+				hasErrors = true
+			}
 		case keyDeps:
 			task.DepsLine = key.Line
-			if err := val.Decode(&task.Deps); err != nil {
-				verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: fmt.Sprintf("task %q: invalid deps", taskName), Cause: err})
+			if !parseDeps(path, taskName, val, &task.Deps, verrs) {
 				hasErrors = true
 			}
 		case keyRun:
@@ -287,12 +492,102 @@ func parseTask(path string, node *yaml.Node, taskName string, verrs *errs.Valida
 				hasErrors = true
 			}
 			task.Run = runItems
+		case keyRunAppend:
+			runItems, ok := parseRunItems(path, val, taskName, verrs)
+			if !ok {
+				hasErrors = true
+			}
+			task.RunAppend = runItems
 		}
 	}
 
+	if task.RunAppend != nil && task.Extends == "" {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("task %q: run_append requires extends", taskName)})
+		hasErrors = true
+	}
+
+	if fileVars != nil {
+		task.Vars = babfile.MergeVarMaps(fileVars, task.Vars)
+	}
 	return task, !hasErrors
 }
 
+func parseDeps(path, taskName string, val *yaml.Node, out *[]babfile.Dep, verrs *errs.ValidationErrors) bool {
+	if val.Kind != yaml.SequenceNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: val.Line, Column: val.Column, Message: fmt.Sprintf("task %q: deps must be a list of task names", taskName)})
+		return false
+	}
+
+	deps := make([]babfile.Dep, 0, len(val.Content))
+	hasErrors := false
+	for i, node := range val.Content {
+		dep, ok := parseDep(path, fmt.Sprintf("task %q: deps[%d]", taskName, i), node, verrs)
+		if !ok {
+			hasErrors = true
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	*out = deps
+	return !hasErrors
+}
+
+func parseDep(path, prefix string, node *yaml.Node, verrs *errs.ValidationErrors) (babfile.Dep, bool) {
+	dep := babfile.Dep{Line: node.Line}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		dep.Task = node.Value
+		return dep, true
+	case yaml.MappingNode:
+	default:
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf("%s: dep must be a task name or a mapping", prefix)})
+		return dep, false
+	}
+
+	hasErrors := false
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		val := node.Content[i+1]
+
+		switch key.Value {
+		case keyTask:
+			dep.Task = val.Value
+		case keyVars:
+			if !parseVarMap(path, val, &dep.Vars, verrs) {
+				hasErrors = true
+			}
+		case keyEnv:
+			if !parseEnvMap(path, val, &dep.Env, verrs) {
+				hasErrors = true
+			}
+		default:
+			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Column: key.Column, Message: fmt.Sprintf("%s: unknown key %q, must be one of: task, vars, env", prefix, key.Value)})
+			hasErrors = true
+		}
+	}
+	if dep.Task == "" && !hasErrors {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf("%s: dep must have 'task'", prefix)})
+		hasErrors = true
+	}
+	return dep, !hasErrors
+}
+
+func parseSources(path, taskName string, val *yaml.Node, out *[]string, verrs *errs.ValidationErrors) bool {
+	var sources []string
+	if val.Kind != yaml.SequenceNode || val.Decode(&sources) != nil {
+		verrs.Add(&errs.ParseError{Path: path, Line: val.Line, Column: val.Column, Message: fmt.Sprintf("task %q: sources must be a list of glob patterns", taskName)})
+		return false
+	}
+	for i, source := range sources {
+		if strings.TrimSpace(source) == "" {
+			verrs.Add(&errs.ParseError{Path: path, Line: val.Content[i].Line, Column: val.Content[i].Column, Message: fmt.Sprintf("task %q: sources[%d] cannot be empty", taskName, i)})
+			return false
+		}
+	}
+	*out = sources
+	return true
+}
+
 func parseRunItems(path string, node *yaml.Node, taskName string, verrs *errs.ValidationErrors) ([]babfile.RunItem, bool) {
 	if node.Kind != yaml.SequenceNode {
 		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("task %q: run must be a sequence", taskName)})
@@ -328,6 +623,8 @@ func parsePromptKey(key, val *yaml.Node, pf *promptFields, path, taskName string
 		pf.placeholder = val.Value
 	case keyValidate:
 		pf.validate = val.Value
+	case keyGlob:
+		pf.glob = val.Value
 	case keyOptions:
 		if err := val.Decode(&pf.options); err != nil {
 			verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: fmt.Sprintf("task %q: run[%d]: invalid options", taskName, index), Cause: err})
@@ -356,6 +653,14 @@ func parsePromptKey(key, val *yaml.Node, pf *promptFields, path, taskName string
 		if !parseBool(path, val, &pf.confirm, verrs) {
 			return false
 		}
+	case keyDefer:
+		if !parseBool(path, val, &pf.deferred, verrs) {
+			return false
+		}
+	case keyRemember:
+		if !parseBool(path, val, &pf.remember, verrs) {
+			return false
+		}
 	}
 	return true
 }
@@ -402,10 +707,16 @@ func parseRunItem(path string, node *yaml.Node, taskName string, index int, verr
 		return nil, false
 	case rf.hasErrors:
 		return nil, false
+	case rf.vars != nil && rf.task == "":
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("task %q: run[%d]: 'vars' is only allowed on 'task' items", taskName, index)})
+		return nil, false
 	case rf.cmd != "":
-		return babfile.CommandRun{Line: rf.line, Cmd: rf.cmd, Dir: rf.dir, Env: rf.env, Silent: rf.silent, Output: rf.output, Platforms: rf.platforms, When: rf.when}, true
+		return babfile.CommandRun{Line: rf.line, Cmd: rf.cmd, Dir: rf.dir, Env: rf.env, Silent: rf.silent, Output: rf.output, Platforms: rf.platforms, When: rf.when, KillTimeout: rf.killTimeout, For: rf.forEach}, true
+	case rf.forEach != nil:
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("task %q: run[%d]: 'for' is only allowed on 'cmd' items", taskName, index)})
+		return nil, false
 	case rf.task != "":
-		return babfile.TaskRun{Line: rf.line, Task: rf.task, Silent: rf.silent, Output: rf.output, Platforms: rf.platforms, When: rf.when}, true
+		return babfile.TaskRun{Line: rf.line, Task: rf.task, Vars: rf.vars, Env: rf.env, Silent: rf.silent, Output: rf.output, Platforms: rf.platforms, When: rf.when}, true
 	case rf.log != "":
 		return buildLogRun(path, rf.line, taskName, index, rf.log, rf.level, rf.platforms, rf.when, verrs)
 	case rf.pf.name != "":
@@ -418,9 +729,12 @@ func parseRunItem(path string, node *yaml.Node, taskName string, index int, verr
 type runFields struct {
 	cmd, task, log, dir, when string
 	env                       map[string]string
+	vars                      babfile.VarMap
 	platforms                 []babfile.Platform
 	level                     babfile.LogLevel
 	silent, output            *bool
+	killTimeout               time.Duration
+	forEach                   any
 	line                      int
 	hasErrors                 bool
 	pf                        promptFields
@@ -444,7 +758,17 @@ func parseRunFields(path string, node *yaml.Node, taskName string, index int, ve
 				rf.dir = val.Value
 			}
 		case keyWhen:
-			rf.when = val.Value
+			if !parseWhen(path, fmt.Sprintf("task %q: run[%d]", taskName, index), val, &rf.when, verrs) {
+				rf.hasErrors = true
+			}
+		case keyKillTimeout:
+			if !parseKillTimeout(path, fmt.Sprintf("task %q: run[%d]", taskName, index), val, &rf.killTimeout, verrs) {
+				rf.hasErrors = true
+			}
+		case keyFor:
+			if !parseFor(path, fmt.Sprintf("task %q: run[%d]", taskName, index), val, &rf.forEach, verrs) {
+				rf.hasErrors = true
+			}
 		case keyTask:
 			rf.task = val.Value
 		case keyLog:
@@ -455,6 +779,10 @@ func parseRunFields(path string, node *yaml.Node, taskName string, index int, ve
 			if !parseEnvMap(path, val, &rf.env, verrs) {
 				rf.hasErrors = true
 			}
+		case keyVars:
+			if !parseVarMap(path, val, &rf.vars, verrs) {
+				rf.hasErrors = true
+			}
 		case keySilent:
 			if !parseBool(path, val, &rf.silent, verrs) {
 				rf.hasErrors = true
@@ -531,7 +859,7 @@ func parseParallelKey(key, val *yaml.Node, pf *parallelFields, path, prefix stri
 		pf.platforms, ok = parsePlatforms(path, key.Line, prefix, val, verrs)
 		return ok
 	case keyWhen:
-		pf.when = val.Value
+		return parseWhen(path, prefix, val, &pf.when, verrs)
 	default:
 		verrs.Add(&errs.ParseError{Path: path, Line: key.Line, Message: fmt.Sprintf("%s: unknown key %q in parallel block", prefix, key.Value)})
 		return false
@@ -629,7 +957,7 @@ func parsePlatforms(path string, line int, prefix string, val *yaml.Node, verrs
 	}
 	for _, p := range platforms {
 		if !p.Valid() {
-			verrs.Add(&errs.ParseError{Path: path, Line: line, Message: fmt.Sprintf("%s: invalid platform %q, must be one of: linux, darwin, windows", prefix, p)})
+			verrs.Add(&errs.ParseError{Path: path, Line: line, Message: fmt.Sprintf("%s: invalid platform %q, must be an OS (%s), os/arch or */arch, optionally prefixed with !", prefix, p, strings.Join(babfile.PlatformNames(), ", "))})
 			return nil, false
 		}
 	}
@@ -669,13 +997,16 @@ func validatePromptBasics(ctx *promptValidationContext, verrs *errs.ValidationEr
 	if !varNameRegex.MatchString(ctx.pf.name) {
 		ctx.addError(verrs, fmt.Sprintf("%s: invalid prompt variable name %q, must match pattern %s", ctx.prefix, ctx.pf.name, babfile.VarNamePattern))
 		hasErrors = true
+	} else if interpolate.IsReserved(ctx.pf.name) {
+		ctx.addError(verrs, fmt.Sprintf("%s: prompt variable name %q is reserved for built-in variables", ctx.prefix, ctx.pf.name))
+		hasErrors = true
 	}
 
 	if ctx.pf.promptType == "" {
 		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: 'type' is required", ctx.prefix, ctx.pf.name))
 		hasErrors = true
 	} else if !ctx.pType.Valid() {
-		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: invalid type %q, must be one of: confirm, input, select, multiselect, password, number", ctx.prefix, ctx.pf.name, ctx.pf.promptType))
+		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: invalid type %q, must be one of: confirm, input, select, multiselect, password, number, text, path, date, time", ctx.prefix, ctx.pf.name, ctx.pf.promptType))
 		hasErrors = true
 	}
 
@@ -753,8 +1084,22 @@ func validatePromptTypeSpecific(ctx *promptValidationContext, verrs *errs.Valida
 		hasErrors = true
 	}
 
-	if ctx.pf.validate != "" && ctx.pType != babfile.PromptTypeInput {
-		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: 'validate' is only valid for input type", ctx.prefix, ctx.pf.name))
+	if ctx.pf.validate != "" && ctx.pType != babfile.PromptTypeInput && ctx.pType != babfile.PromptTypeText {
+		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: 'validate' is only valid for input/text types", ctx.prefix, ctx.pf.name))
+		hasErrors = true
+	}
+
+	if ctx.pf.glob != "" {
+		if ctx.pType != babfile.PromptTypePath {
+			ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: 'glob' is only valid for path type", ctx.prefix, ctx.pf.name))
+			hasErrors = true
+		} else if _, err := filepath.Match(ctx.pf.glob, ""); err != nil {
+			ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: invalid glob %q: %v", ctx.prefix, ctx.pf.name, ctx.pf.glob, err))
+			hasErrors = true
+		}
+	}
+
+	if !validatePromptDateTimeDefault(ctx, verrs) {
 		hasErrors = true
 	}
 
@@ -763,6 +1108,11 @@ func validatePromptTypeSpecific(ctx *promptValidationContext, verrs *errs.Valida
 		hasErrors = true
 	}
 
+	if ctx.pf.remember != nil && *ctx.pf.remember && ctx.pType == babfile.PromptTypePassword {
+		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: 'remember' is not allowed for password type", ctx.prefix, ctx.pf.name))
+		hasErrors = true
+	}
+
 	if ctx.pType == babfile.PromptTypeNumber && ctx.pf.dflt != "" {
 		if _, err := strconv.Atoi(ctx.pf.dflt); err != nil {
 			ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: default value %q must be a valid integer for number type", ctx.prefix, ctx.pf.name, ctx.pf.dflt))
@@ -773,6 +1123,31 @@ func validatePromptTypeSpecific(ctx *promptValidationContext, verrs *errs.Valida
 	return !hasErrors
 }
 
+func validatePromptDateTimeDefault(ctx *promptValidationContext, verrs *errs.ValidationErrors) bool {
+	if ctx.pf.dflt == "" || interpolate.ContainsVarRef(ctx.pf.dflt) {
+		return true
+	}
+
+	var layout, keyword string
+	switch ctx.pType {
+	case babfile.PromptTypeDate:
+		layout, keyword = babfile.PromptDateLayout, babfile.PromptDateToday
+	case babfile.PromptTypeTime:
+		layout, keyword = babfile.PromptTimeLayout, babfile.PromptTimeNow
+	default:
+		return true
+	}
+
+	if ctx.pf.dflt == keyword {
+		return true
+	}
+	if _, err := time.Parse(layout, ctx.pf.dflt); err != nil {
+		ctx.addError(verrs, fmt.Sprintf("%s: prompt %q: default value %q must be %q or match the format %s for %s type", ctx.prefix, ctx.pf.name, ctx.pf.dflt, keyword, layout, ctx.pf.promptType))
+		return false
+	}
+	return true
+}
+
 func stringInSlice(s string, slice []string) bool {
 	for _, item := range slice {
 		if item == s {
@@ -811,9 +1186,12 @@ func validatePromptRun(path string, line int, taskName string, index int, pf pro
 		Options:     pf.options,
 		Placeholder: pf.placeholder,
 		Validate:    pf.validate,
+		Glob:        pf.glob,
 		Min:         pf.min,
 		Max:         pf.max,
 		Confirm:     pf.confirm,
+		Defer:       pf.deferred != nil && *pf.deferred,
+		Remember:    pf.remember != nil && *pf.remember,
 		When:        when,
 	}, true
 }
@@ -833,6 +1211,107 @@ func parseIncludes(path string, node *yaml.Node, schema *babfile.Schema, verrs *
 			verrs.Add(&errs.ParseError{Path: path, Line: nameNode.Line, Message: fmt.Sprintf("include %q", nameNode.Value), Cause: err})
 			continue
 		}
+		if !parseIncludeMaps(path, incNode, &inc, verrs) {
+			continue
+		}
+		if msg := validateInclude(inc); msg != "" {
+			verrs.Add(&errs.ParseError{Path: path, Line: nameNode.Line, Message: fmt.Sprintf("include %q: %s", nameNode.Value, msg)})
+			continue
+		}
 		schema.Includes[nameNode.Value] = inc
 	}
 }
+
+func parseIncludeMaps(path string, node *yaml.Node, inc *babfile.Include, verrs *errs.ValidationErrors) bool {
+	ok := true
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case keyVars:
+			ok = parseVarMap(path, node.Content[i+1], &inc.Vars, verrs) && ok
+		case keyEnv:
+			ok = parseEnvMap(path, node.Content[i+1], &inc.Env, verrs) && ok
+		}
+	}
+	return ok
+}
+
+func validateInclude(inc babfile.Include) string {
+	for _, alias := range inc.Aliases {
+		if !taskNameRegex.MatchString(alias) {
+			return fmt.Sprintf("invalid alias %q, must match pattern %s", alias, babfile.TaskNamePattern)
+		}
+	}
+	sources := 0
+	for _, source := range []string{inc.Babfile, inc.Git, inc.Glob} {
+		if source != "" {
+			sources++
+		}
+	}
+	switch {
+	case inc.Flatten && len(inc.Aliases) > 0:
+		return "'aliases' cannot be combined with 'flatten'"
+	case sources > 1:
+		return "only one of 'babfile', 'git' and 'glob' can be set"
+	case sources == 0:
+		return "requires 'babfile', 'git' or 'glob'"
+	case inc.Glob != "" && inc.Dir != "":
+		return "'dir' cannot be combined with 'glob'"
+	case !inc.IsRemote() && (inc.Ref != "" || inc.Path != ""):
+		return "'ref' and 'path' require 'git'"
+	case inc.Path != "" && (filepath.IsAbs(inc.Path) || !filepath.IsLocal(inc.Path)):
+		return fmt.Sprintf("path %q must be relative to the repository root", inc.Path)
+	}
+	return ""
+}
+
+func parseProfiles(path string, node *yaml.Node, schema *babfile.Schema, verrs *errs.ValidationErrors) {
+	if node.Kind != yaml.MappingNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: "profiles must be a mapping"})
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		nameNode := node.Content[i]
+		if !profileNameRegex.MatchString(nameNode.Value) {
+			verrs.Add(&errs.ParseError{Path: path, Line: nameNode.Line, Message: fmt.Sprintf("invalid profile name %q, must match pattern %s", nameNode.Value, babfile.ProfileNamePattern)})
+			continue
+		}
+		if profile, ok := parseProfile(path, nameNode.Value, node.Content[i+1], verrs); ok {
+			schema.Profiles[nameNode.Value] = profile
+		}
+	}
+}
+
+func parseProfile(path, name string, node *yaml.Node, verrs *errs.ValidationErrors) (babfile.Profile, bool) {
+	var profile babfile.Profile
+	if node.Kind != yaml.MappingNode {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: fmt.Sprintf("profile %q: must be a mapping", name)})
+		return profile, false
+	}
+
+	ok := true
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		val := node.Content[i+1]
+
+		switch key.Value {
+		case keyDesc:
+			profile.Desc = val.Value
+		case keyVars:
+			ok = parseVarMap(path, val, &profile.Vars, verrs) && ok
+		case keyEnv:
+			ok = parseEnvMap(path, val, &profile.Env, verrs) && ok
+		case keyDotenv:
+			if val.Kind != yaml.SequenceNode || val.Decode(&profile.Dotenv) != nil || slices.Contains(profile.Dotenv, "") {
+				verrs.Add(&errs.ParseError{Path: path, Line: val.Line, Message: fmt.Sprintf("profile %q: dotenv must be a list of file paths", name)})
+				ok = false
+			}
+		case keyConfirm:
+			if err := val.Decode(&profile.Confirm); err != nil || val.Kind != yaml.ScalarNode {
+				verrs.Add(&errs.ParseError{Path: path, Line: val.Line, Message: fmt.Sprintf("profile %q: confirm must be a boolean", name)})
+				ok = false
+			}
+		}
+	}
+	return profile, ok
+}
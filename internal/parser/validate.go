@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"cmp"
+
 	"github.com/bab-sh/bab/internal/babfile"
 	"github.com/bab-sh/bab/internal/errs"
 )
@@ -29,7 +31,7 @@ func validateAliases(path string, tasks babfile.TaskMap, verrs *errs.ValidationE
 
 			if tasks.Has(alias) {
 				verrs.Add(&errs.AliasConflictError{
-					Path:     path,
+					Path:     taskPath(path, task),
 					Line:     task.Line,
 					Alias:    alias,
 					TaskName: name,
@@ -39,7 +41,7 @@ func validateAliases(path string, tasks babfile.TaskMap, verrs *errs.ValidationE
 
 			if existing, exists := aliasToTask[alias]; exists {
 				verrs.Add(&errs.DuplicateAliasError{
-					Path:         path,
+					Path:         taskPath(path, task),
 					Line:         task.Line,
 					Alias:        alias,
 					TaskName:     name,
@@ -57,15 +59,12 @@ func validateAliases(path string, tasks babfile.TaskMap, verrs *errs.ValidationE
 func validateDependencies(path string, tasks babfile.TaskMap, verrs *errs.ValidationErrors) {
 	for name, task := range tasks {
 		for _, dep := range task.Deps {
-			if !tasks.Has(dep) {
-				line := task.DepsLine
-				if line == 0 {
-					line = task.Line
-				}
+			if !tasks.Has(dep.Task) {
+				line := cmp.Or(dep.Line, task.DepsLine, task.Line)
 				verrs.Add(&errs.TaskNotFoundError{
-					Path:         path,
+					Path:         taskPath(path, task),
 					Line:         line,
-					TaskName:     dep,
+					TaskName:     dep.Task,
 					ReferencedBy: name,
 					Available:    tasks.Names(),
 				})
@@ -76,7 +75,7 @@ func validateDependencies(path string, tasks babfile.TaskMap, verrs *errs.Valida
 
 func validateRunTaskRefs(path string, tasks babfile.TaskMap, verrs *errs.ValidationErrors) {
 	for name, task := range tasks {
-		validateRunItemTaskRefs(path, name, task.Run, tasks, verrs)
+		validateRunItemTaskRefs(taskPath(path, task), name, task.Run, tasks, verrs)
 	}
 }
 
@@ -140,8 +139,8 @@ func validateRunCycles(path string, tasks babfile.TaskMap, verrs *errs.Validatio
 		}
 
 		for _, dep := range task.Deps {
-			if recStack[dep] {
-				chain = append(chain, dep)
+			if recStack[dep.Task] {
+				chain = append(chain, dep.Task)
 				verrs.Add(&errs.CircularDepError{
 					Path:  path,
 					Type:  "dependency",
@@ -149,8 +148,8 @@ func validateRunCycles(path string, tasks babfile.TaskMap, verrs *errs.Validatio
 				})
 				return
 			}
-			if !visited[dep] && tasks.Has(dep) {
-				dfs(dep, chain)
+			if !visited[dep.Task] && tasks.Has(dep.Task) {
+				dfs(dep.Task, chain)
 			}
 		}
 
@@ -164,3 +163,10 @@ func validateRunCycles(path string, tasks babfile.TaskMap, verrs *errs.Validatio
 		}
 	}
 }
+
+func taskPath(path string, task *babfile.Task) string {
+	if task.SourcePath != "" {
+		return task.SourcePath
+	}
+	return path
+}
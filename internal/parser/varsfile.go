@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/dotenv"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/interpolate"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+func parseVarsFile(path string, node *yaml.Node, verrs *errs.ValidationErrors) (babfile.VarMap, bool) {
+	var specs []*yaml.Node
+	switch node.Kind {
+	case yaml.ScalarNode:
+		specs = []*yaml.Node{node}
+	case yaml.SequenceNode:
+		specs = node.Content
+	}
+	if len(specs) == 0 {
+		verrs.Add(&errs.ParseError{Path: path, Line: node.Line, Message: "vars_file must be a path or a list of paths"})
+		return nil, false
+	}
+
+	vars := make(babfile.VarMap)
+	ok := true
+	for _, spec := range specs {
+		if spec.Kind != yaml.ScalarNode || strings.TrimSpace(spec.Value) == "" {
+			verrs.Add(&errs.ParseError{Path: path, Line: spec.Line, Message: "vars_file must be a path or a list of paths"})
+			ok = false
+			continue
+		}
+
+		file, keyPath, _ := strings.Cut(spec.Value, "#")
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		loaded, err := loadVarsFile(file, keyPath)
+		if err != nil {
+			var fileErrs *errs.ValidationErrors
+			var parseErr *errs.ParseError
+			switch {
+			case errors.As(err, &fileErrs):
+				verrs.Errors = append(verrs.Errors, fileErrs.Errors...)
+			case errors.As(err, &parseErr):
+				verrs.Add(parseErr)
+			default:
+				verrs.Add(&errs.ParseError{Path: path, Line: spec.Line, Message: "invalid vars_file", Cause: fmt.Errorf("vars_file %q: %w", spec.Value, err)})
+			}
+			ok = false
+			continue
+		}
+		vars = babfile.MergeVarMaps(vars, loaded)
+	}
+	return vars, ok
+}
+
+func loadVarsFile(file, keyPath string) (babfile.VarMap, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errs.ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if isDotenvFile(file) {
+		if keyPath != "" {
+			return nil, fmt.Errorf("key path %q is not supported for dotenv files", keyPath)
+		}
+		return loadDotenvVars(file, data)
+	}
+
+	switch filepath.Ext(file) {
+	case ".yml", ".yaml", ".json":
+	default:
+		return nil, errors.New("unsupported file type, use .yml, .yaml, .json or .env")
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, babfileError(file, err)
+	}
+	if len(root.Content) == 0 {
+		return babfile.VarMap{}, nil
+	}
+
+	node := root.Content[0]
+	var name string
+	if keyPath != "" {
+		for _, key := range strings.Split(keyPath, ".") {
+			next := mappingValue(node, key)
+			if next == nil {
+				return nil, fmt.Errorf("key %q not found", keyPath)
+			}
+			node, name = next, key
+		}
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	verrs := &errs.ValidationErrors{}
+	if name != "" && node.Kind != yaml.MappingNode {
+		if !isVarName(name) {
+			return nil, fmt.Errorf("key %q can't be used as a variable name", name)
+		}
+		value, ok := parseVarValue(file, name, node, verrs)
+		if !ok {
+			return nil, verrs
+		}
+		return babfile.VarMap{name: value}, nil
+	}
+
+	var vars babfile.VarMap
+	if !parseVarMap(file, varKeys(file, node), &vars, verrs) {
+		return nil, verrs
+	}
+	return vars, nil
+}
+
+func varKeys(file string, node *yaml.Node) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return node
+	}
+	kept := *node
+	kept.Content = make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; !isVarName(key) {
+			log.Debug("Skipping vars_file key, not a valid variable name", "file", file, "key", key)
+			continue
+		}
+		kept.Content = append(kept.Content, node.Content[i], node.Content[i+1])
+	}
+	return &kept
+}
+
+func isVarName(name string) bool {
+	return varNameRegex.MatchString(name) && !interpolate.IsReserved(name)
+}
+
+func loadDotenvVars(file string, data []byte) (babfile.VarMap, error) {
+	values, err := dotenv.Parse(file, data)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(babfile.VarMap, len(values))
+	for name, value := range values {
+		if !varNameRegex.MatchString(name) {
+			return nil, &errs.ParseError{Path: file, Message: fmt.Sprintf("invalid vars name %q, must match pattern %s", name, babfile.VarNamePattern)}
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isDotenvFile(file string) bool {
+	base := filepath.Base(file)
+	return base == ".env" || strings.HasPrefix(base, ".env.") || filepath.Ext(base) == ".env"
+}
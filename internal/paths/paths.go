@@ -1,9 +1,30 @@
 package paths
 
-import "github.com/adrg/xdg"
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/adrg/xdg"
+)
 
 const appName = "bab"
 
 func CacheFile(name string) (string, error) {
 	return xdg.CacheFile(appName + "/" + name)
 }
+
+func CacheDir(name string) (string, error) {
+	dir := filepath.Join(xdg.CacheHome, appName, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func StateFile(name string) (string, error) {
+	return xdg.StateFile(appName + "/" + name)
+}
+
+func ConfigDir() string {
+	return filepath.Join(xdg.ConfigHome, appName)
+}
@@ -28,3 +28,48 @@ func TestCacheFile(t *testing.T) {
 		t.Error("CacheFile() should create parent directory")
 	}
 }
+
+func TestStateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", tmpDir)
+	xdg.Reload()
+
+	path, err := StateFile("test.json")
+	if err != nil {
+		t.Fatalf("StateFile() error = %v", err)
+	}
+
+	want := filepath.Join(tmpDir, "bab", "test.json")
+	if path != want {
+		t.Errorf("StateFile() = %q, want %q", path, want)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", tmpDir)
+	xdg.Reload()
+
+	dir, err := CacheDir("includes")
+	if err != nil {
+		t.Fatalf("CacheDir() error = %v", err)
+	}
+
+	want := filepath.Join(tmpDir, "bab", "includes")
+	if dir != want {
+		t.Errorf("CacheDir() = %q, want %q", dir, want)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Error("CacheDir() should create the directory")
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	xdg.Reload()
+
+	if dir, want := ConfigDir(), filepath.Join(tmpDir, "bab"); dir != want {
+		t.Errorf("ConfigDir() = %q, want %q", dir, want)
+	}
+}
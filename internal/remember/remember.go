@@ -0,0 +1,125 @@
+package remember
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/bab-sh/bab/internal/paths"
+)
+
+const stateFileName = "answers.json"
+
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	answers map[string]map[string]string
+}
+
+func Load() (*Store, error) {
+	path, err := paths.StateFile(stateFileName)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+func LoadFile(path string) (*Store, error) {
+	s := &Store{path: path, answers: make(map[string]map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading remembered answers: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.answers); err != nil {
+		return nil, fmt.Errorf("parsing remembered answers: %w", err)
+	}
+	if s.answers == nil {
+		s.answers = make(map[string]map[string]string)
+	}
+	return s, nil
+}
+
+func (s *Store) Get(babfile, prompt string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.answers[babfile][prompt]
+	return v, ok
+}
+
+func (s *Store) Set(babfile, prompt, value string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.answers[babfile] == nil {
+		s.answers[babfile] = make(map[string]string)
+	}
+	s.answers[babfile][prompt] = value
+	return s.save()
+}
+
+func (s *Store) Forget(babfile string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.answers[babfile]; !ok {
+		return nil
+	}
+	delete(s.answers, babfile)
+	return s.save()
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.answers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "answers-*.tmp")
+	if err != nil {
+		return fmt.Errorf("saving remembered answers: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("saving remembered answers: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("saving remembered answers: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		_ = os.Remove(s.path)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("saving remembered answers: %w", err)
+	}
+	return nil
+}
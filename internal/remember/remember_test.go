@@ -0,0 +1,65 @@
+package remember
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "answers.json")
+
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if err := s.Set("/a/Babfile.yml", "env", "prod"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := s.Set("/b/Babfile.yml", "env", "dev"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	reloaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if got, ok := reloaded.Get("/a/Babfile.yml", "env"); !ok || got != "prod" {
+		t.Errorf("Get() = %q, %v, want %q, true", got, ok, "prod")
+	}
+
+	if err := reloaded.Forget("/a/Babfile.yml"); err != nil {
+		t.Fatalf("Forget() error = %v", err)
+	}
+
+	final, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if _, ok := final.Get("/a/Babfile.yml", "env"); ok {
+		t.Error("expected answers for /a/Babfile.yml to be forgotten")
+	}
+	if got, ok := final.Get("/b/Babfile.yml", "env"); !ok || got != "dev" {
+		t.Errorf("Get() = %q, %v, want %q, true", got, ok, "dev")
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected error for invalid state file")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	if _, ok := s.Get("Babfile.yml", "env"); ok {
+		t.Error("nil Store should not return answers")
+	}
+	if err := s.Set("Babfile.yml", "env", "prod"); err != nil {
+		t.Errorf("nil Store Set() error = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const LockFileName = "Babfile.lock"
+
+const lockHeader = "# Generated by bab. Run \"bab includes update\" to refresh pinned commits.\n"
+
+type LockEntry struct {
+	Git    string `yaml:"git"`
+	Ref    string `yaml:"ref,omitempty"`
+	Commit string `yaml:"commit"`
+}
+
+type Lock struct {
+	path    string
+	entries []LockEntry
+	used    map[LockEntry]bool
+	changed bool
+}
+
+type lockFile struct {
+	Includes []LockEntry `yaml:"includes"`
+}
+
+func LockPath(babfilePath string) string {
+	return filepath.Join(filepath.Dir(babfilePath), LockFileName)
+}
+
+func LoadLock(path string) (*Lock, error) {
+	l := &Lock{path: path, used: make(map[LockEntry]bool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", LockFileName, err)
+	}
+
+	var lf lockFile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", LockFileName, err)
+	}
+	l.entries = lf.Includes
+	return l, nil
+}
+
+func (l *Lock) Path() string {
+	return l.path
+}
+
+func (l *Lock) Lookup(url, ref string) (string, bool) {
+	for _, e := range l.entries {
+		if e.Git == url && e.Ref == ref {
+			return e.Commit, true
+		}
+	}
+	return "", false
+}
+
+func (l *Lock) Set(url, ref, commit string) {
+	entry := LockEntry{Git: url, Ref: ref, Commit: commit}
+	l.used[entry] = true
+	for i, e := range l.entries {
+		if e.Git == url && e.Ref == ref {
+			if e.Commit != commit {
+				l.entries[i].Commit = commit
+				l.changed = true
+			}
+			return
+		}
+	}
+	l.entries = append(l.entries, entry)
+	l.changed = true
+}
+
+func (l *Lock) Entries() []LockEntry {
+	entries := make([]LockEntry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+func (l *Lock) Prune() {
+	kept := l.entries[:0]
+	for _, e := range l.entries {
+		if l.used[e] {
+			kept = append(kept, e)
+		} else {
+			l.changed = true
+		}
+	}
+	l.entries = kept
+}
+
+func (l *Lock) Save() error {
+	if !l.changed {
+		return nil
+	}
+	if len(l.entries) == 0 {
+		if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing %s: %w", LockFileName, err)
+		}
+		l.changed = false
+		return nil
+	}
+
+	sort.Slice(l.entries, func(i, j int) bool {
+		if l.entries[i].Git != l.entries[j].Git {
+			return l.entries[i].Git < l.entries[j].Git
+		}
+		return l.entries[i].Ref < l.entries[j].Ref
+	})
+
+	data, err := yaml.Marshal(lockFile{Includes: l.entries})
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", LockFileName, err)
+	}
+	if err := os.WriteFile(l.path, append([]byte(lockHeader), data...), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", LockFileName, err)
+	}
+	l.changed = false
+	return nil
+}
@@ -0,0 +1,172 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bab-sh/bab/internal/git"
+	"github.com/bab-sh/bab/internal/paths"
+	"github.com/charmbracelet/log"
+)
+
+const cacheName = "includes"
+
+var ErrNotCached = errors.New("remote include is not cached")
+
+type Mode int
+
+const (
+	ReadOnly Mode = iota
+	Pin
+	Update
+)
+
+type Fetcher struct {
+	Lock      *Lock
+	Mode      Mode
+	CacheDir  string
+	Unfetched []string
+	fetched   map[string]bool
+}
+
+func NewFetcher(lock *Lock, mode Mode) *Fetcher {
+	return &Fetcher{Lock: lock, Mode: mode, fetched: make(map[string]bool)}
+}
+
+func (f *Fetcher) Fetch(url, ref string) (string, error) {
+	cacheDir, err := f.cacheDir()
+	if err != nil {
+		return "", err
+	}
+	repo := filepath.Join(cacheDir, "repos", repoKey(url))
+
+	commit, locked := f.Lock.Lookup(url, ref)
+	if !locked && f.Mode == ReadOnly {
+		f.skip(url)
+		return "", fmt.Errorf("%w: %s is not pinned in %s", ErrNotCached, url, LockFileName)
+	}
+	if !locked || f.Mode == Update {
+		if err := f.sync(url, repo); err != nil {
+			return "", err
+		}
+		commit, err = git.ResolveCommit(repo, refOrHead(ref))
+		if err != nil {
+			return "", fmt.Errorf("resolving ref %q of %s: %w", refOrHead(ref), url, err)
+		}
+		log.Debug("Resolved remote include", "git", url, "ref", refOrHead(ref), "commit", commit)
+	}
+	f.Lock.Set(url, ref, commit)
+
+	dest := filepath.Join(cacheDir, "checkouts", commit)
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+
+	if !git.HasCommit(repo, commit) {
+		if f.Mode == ReadOnly {
+			f.skip(url)
+			return "", fmt.Errorf("%w: commit %s of %s", ErrNotCached, commit, url)
+		}
+		if err := f.sync(url, repo); err != nil {
+			return "", err
+		}
+		if !git.HasCommit(repo, commit) {
+			return "", fmt.Errorf("commit %s of %s not found, run \"bab includes update\"", commit, url)
+		}
+	}
+	if err := export(repo, commit, dest); err != nil {
+		return "", fmt.Errorf("checking out %s at %s: %w", url, commit, err)
+	}
+	return dest, nil
+}
+
+func (f *Fetcher) skip(url string) {
+	if !slices.Contains(f.Unfetched, url) {
+		f.Unfetched = append(f.Unfetched, url)
+	}
+}
+
+func (f *Fetcher) InCache(path string) bool {
+	if f.CacheDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(f.CacheDir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func (f *Fetcher) sync(url, repo string) error {
+	if f.fetched[repo] {
+		return nil
+	}
+	if _, err := os.Stat(repo); errors.Is(err, os.ErrNotExist) {
+		log.Debug("Cloning remote include", "git", url)
+		if err := os.MkdirAll(filepath.Dir(repo), 0o755); err != nil {
+			return err
+		}
+		if err := git.Mirror(url, repo); err != nil {
+			_ = os.RemoveAll(repo)
+			return fmt.Errorf("fetching %s: %w", url, err)
+		}
+	} else {
+		log.Debug("Fetching remote include", "git", url)
+		if err := git.Fetch(repo); err != nil {
+			if f.Mode == Update {
+				return fmt.Errorf("fetching %s: %w", url, err)
+			}
+			log.Warn("Could not fetch remote include, using cache", "git", url, "error", err)
+		}
+	}
+	f.fetched[repo] = true
+	return nil
+}
+
+func (f *Fetcher) cacheDir() (string, error) {
+	if f.CacheDir != "" {
+		return f.CacheDir, nil
+	}
+	dir, err := paths.CacheDir(cacheName)
+	if err != nil {
+		return "", fmt.Errorf("creating include cache: %w", err)
+	}
+	f.CacheDir = dir
+	return dir, nil
+}
+
+func export(repo, commit, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), commit+"-*")
+	if err != nil {
+		return err
+	}
+	if err := git.Export(repo, commit, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.RemoveAll(tmp)
+		if _, statErr := os.Stat(dest); statErr == nil {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func repoKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func refOrHead(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
@@ -0,0 +1,184 @@
+package remote
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=bab", "-c", "user.email=bab@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func commitFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "Babfile.yml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, dir, "add", "-A")
+	gitCmd(t, dir, "commit", "-q", "-m", "update")
+	return gitCmd(t, dir, "rev-parse", "HEAD")
+}
+
+func setupRepo(t *testing.T) (work, url string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	work = t.TempDir()
+	gitCmd(t, work, "init", "-q", "-b", "main")
+	return work, "file://" + filepath.ToSlash(work)
+}
+
+func TestFetchPinsAndReusesCommit(t *testing.T) {
+	work, url := setupRepo(t)
+	first := commitFile(t, work, "tasks:\n  a:\n    run:\n      - cmd: echo one\n")
+	gitCmd(t, work, "tag", "v1")
+
+	lock, err := LoadLock(filepath.Join(t.TempDir(), LockFileName))
+	if err != nil {
+		t.Fatalf("LoadLock() error: %v", err)
+	}
+	f := NewFetcher(lock, Pin)
+	f.CacheDir = t.TempDir()
+
+	dir, err := f.Fetch(url, "main")
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	if filepath.Base(dir) != first {
+		t.Errorf("expected checkout named after commit %s, got %s", first, dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Babfile.yml")); err != nil {
+		t.Errorf("expected Babfile.yml in checkout: %v", err)
+	}
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	second := commitFile(t, work, "tasks:\n  a:\n    run:\n      - cmd: echo two\n")
+
+	lock, err = LoadLock(lock.Path())
+	if err != nil {
+		t.Fatalf("LoadLock() error: %v", err)
+	}
+	if commit, ok := lock.Lookup(url, "main"); !ok || commit != first {
+		t.Fatalf("expected pinned commit %s, got %s", first, commit)
+	}
+
+	f = NewFetcher(lock, Pin)
+	f.CacheDir = filepath.Dir(filepath.Dir(dir))
+	if dir, err = f.Fetch(url, "main"); err != nil || filepath.Base(dir) != first {
+		t.Errorf("expected locked commit %s, got %s (%v)", first, dir, err)
+	}
+
+	f = NewFetcher(lock, Update)
+	f.CacheDir = filepath.Dir(filepath.Dir(dir))
+	if dir, err = f.Fetch(url, "main"); err != nil || filepath.Base(dir) != second {
+		t.Errorf("expected updated commit %s, got %s (%v)", second, dir, err)
+	}
+	if dir, err = f.Fetch(url, "v1"); err != nil || filepath.Base(dir) != first {
+		t.Errorf("expected tag commit %s, got %s (%v)", first, dir, err)
+	}
+}
+
+func TestFetchOfflineFromCache(t *testing.T) {
+	work, url := setupRepo(t)
+	commit := commitFile(t, work, "tasks:\n  a:\n    run:\n      - cmd: echo one\n")
+
+	cacheDir := t.TempDir()
+	lock, _ := LoadLock(filepath.Join(t.TempDir(), LockFileName))
+	f := NewFetcher(lock, Pin)
+	f.CacheDir = cacheDir
+	if _, err := f.Fetch(url, ""); err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+
+	if err := os.RemoveAll(work); err != nil {
+		t.Fatal(err)
+	}
+
+	f = NewFetcher(lock, Pin)
+	f.CacheDir = cacheDir
+	dir, err := f.Fetch(url, "")
+	if err != nil {
+		t.Fatalf("Fetch() offline error: %v", err)
+	}
+	if filepath.Base(dir) != commit {
+		t.Errorf("expected cached commit %s, got %s", commit, dir)
+	}
+
+	f = NewFetcher(lock, ReadOnly)
+	f.CacheDir = cacheDir
+	if dir, err := f.Fetch(url, ""); err != nil || filepath.Base(dir) != commit {
+		t.Errorf("expected read-only fetch to use cached commit %s, got %s (%v)", commit, dir, err)
+	}
+
+	empty, _ := LoadLock(filepath.Join(t.TempDir(), LockFileName))
+	f = NewFetcher(empty, ReadOnly)
+	f.CacheDir = cacheDir
+	if _, err := f.Fetch(url, ""); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached for an unpinned include, got %v", err)
+	}
+
+	f = NewFetcher(lock, Update)
+	f.CacheDir = cacheDir
+	if _, err := f.Fetch(url, ""); err == nil {
+		t.Error("expected update to fail when the repository is unreachable")
+	}
+}
+
+func TestFetchUnknownRef(t *testing.T) {
+	work, url := setupRepo(t)
+	commitFile(t, work, "tasks: {}\n")
+
+	lock, _ := LoadLock(filepath.Join(t.TempDir(), LockFileName))
+	f := NewFetcher(lock, Pin)
+	f.CacheDir = t.TempDir()
+	_, err := f.Fetch(url, "v9.9.9")
+	if err == nil || !strings.Contains(err.Error(), `resolving ref "v9.9.9"`) {
+		t.Errorf("expected unknown ref error, got %v", err)
+	}
+}
+
+func TestLockSaveAndPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	lock, err := LoadLock(path)
+	if err != nil {
+		t.Fatalf("LoadLock() error: %v", err)
+	}
+	lock.Set("https://example.com/b.git", "v1", "bbb")
+	lock.Set("https://example.com/a.git", "", "aaa")
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Generated by bab") || strings.Index(string(data), "a.git") > strings.Index(string(data), "b.git") {
+		t.Errorf("unexpected lock file:\n%s", data)
+	}
+
+	lock, _ = LoadLock(path)
+	lock.Set("https://example.com/a.git", "", "aaa")
+	lock.Prune()
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	lock, _ = LoadLock(path)
+	if entries := lock.Entries(); len(entries) != 1 || entries[0].Commit != "aaa" {
+		t.Errorf("expected only the used entry, got %v", entries)
+	}
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type suiteKey struct{}
+
+type caseKey struct{}
+
+type Recorder struct {
+	mu       sync.Mutex
+	commands bool
+	root     *Suite
+	suites   []*Suite
+}
+
+type Suite struct {
+	Name  string
+	Cases []*Case
+	Time  time.Duration
+	start time.Time
+	rec   *Recorder
+}
+
+type Case struct {
+	Name      string
+	ClassName string
+	Time      time.Duration
+	Failure   string
+	Skipped   string
+	start     time.Time
+	rec       *Recorder
+	parent    *Case
+	output    bytes.Buffer
+}
+
+func New(commands bool) *Recorder {
+	r := &Recorder{commands: commands}
+	r.root = &Suite{Name: "bab", start: time.Now(), rec: r}
+	r.suites = []*Suite{r.root}
+	return r
+}
+
+func (r *Recorder) StartSuite(ctx context.Context, name string) (context.Context, *Suite) {
+	if r == nil {
+		return ctx, nil
+	}
+	s := &Suite{Name: name, start: time.Now(), rec: r}
+	r.mu.Lock()
+	r.suites = append(r.suites, s)
+	r.mu.Unlock()
+	return context.WithValue(ctx, suiteKey{}, s), s
+}
+
+func (r *Recorder) StartTask(ctx context.Context, name string) (context.Context, *Case) {
+	if r == nil {
+		return ctx, nil
+	}
+	c := r.addCase(ctx, name, nil)
+	return context.WithValue(ctx, caseKey{}, c), c
+}
+
+func (r *Recorder) StartCommand(ctx context.Context, cmd string) *Case {
+	if r == nil || !r.commands {
+		return nil
+	}
+	return r.addCase(ctx, cmd, CurrentCase(ctx))
+}
+
+func (r *Recorder) addCase(ctx context.Context, name string, parent *Case) *Case {
+	suite := r.suiteFrom(ctx)
+	c := &Case{
+		Name:      name,
+		ClassName: suite.Name,
+		start:     time.Now(),
+		rec:       r,
+		parent:    parent,
+	}
+	r.mu.Lock()
+	suite.Cases = append(suite.Cases, c)
+	r.mu.Unlock()
+	return c
+}
+
+func (r *Recorder) suiteFrom(ctx context.Context) *Suite {
+	if s, ok := ctx.Value(suiteKey{}).(*Suite); ok && s.rec == r {
+		return s
+	}
+	return r.root
+}
+
+func CurrentCase(ctx context.Context) *Case {
+	c, _ := ctx.Value(caseKey{}).(*Case)
+	return c
+}
+
+func Capture(ctx context.Context, cmdCase *Case) io.Writer {
+	target := cmdCase
+	if target == nil {
+		target = CurrentCase(ctx)
+	}
+	if target == nil {
+		return nil
+	}
+	return &caseWriter{c: target}
+}
+
+func (s *Suite) Finish() {
+	if s == nil {
+		return
+	}
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.Time = time.Since(s.start)
+}
+
+func (c *Case) Finish(err error) {
+	if c == nil {
+		return
+	}
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.Time = time.Since(c.start)
+	if err != nil {
+		c.Failure = err.Error()
+	}
+}
+
+func (c *Case) Skip(reason string) {
+	if c == nil {
+		return
+	}
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	if reason == "" {
+		reason = "skipped"
+	}
+	c.Skipped = reason
+}
+
+func (c *Case) Output() string {
+	if c == nil {
+		return ""
+	}
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	return c.output.String()
+}
+
+type caseWriter struct {
+	c *Case
+}
+
+func (w *caseWriter) Write(p []byte) (int, error) {
+	w.c.rec.mu.Lock()
+	defer w.c.rec.mu.Unlock()
+	for c := w.c; c != nil; c = c.parent {
+		c.output.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNilRecorderIsNoop(t *testing.T) {
+	var r *Recorder
+	ctx := context.Background()
+
+	gotCtx, c := r.StartTask(ctx, "build")
+	if gotCtx != ctx || c != nil {
+		t.Error("nil recorder should return the original context and a nil case")
+	}
+	c.Finish(errors.New("boom"))
+	c.Skip("skipped")
+
+	if cc := r.StartCommand(ctx, "echo hi"); cc != nil {
+		t.Error("nil recorder should not start command cases")
+	}
+	if w := Capture(ctx, nil); w != nil {
+		t.Error("Capture() without a case should return nil")
+	}
+
+	_, s := r.StartSuite(ctx, "parallel")
+	s.Finish()
+}
+
+func TestCaptureWritesToTaskAndCommand(t *testing.T) {
+	r := New(true)
+	ctx, task := r.StartTask(context.Background(), "build")
+	cmd := r.StartCommand(ctx, "go build")
+
+	w := Capture(ctx, cmd)
+	_, _ = fmt.Fprint(w, "compiling\n")
+
+	if got := cmd.Output(); got != "compiling\n" {
+		t.Errorf("command output = %q, want %q", got, "compiling\n")
+	}
+	if got := task.Output(); got != "compiling\n" {
+		t.Errorf("task output = %q, want %q", got, "compiling\n")
+	}
+}
+
+func TestCommandsDisabled(t *testing.T) {
+	r := New(false)
+	ctx, task := r.StartTask(context.Background(), "build")
+	if cmd := r.StartCommand(ctx, "go build"); cmd != nil {
+		t.Error("StartCommand() should return nil when commands are disabled")
+	}
+
+	_, _ = fmt.Fprint(Capture(ctx, nil), "output")
+	if got := task.Output(); got != "output" {
+		t.Errorf("task output = %q, want %q", got, "output")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := New(false)
+	ctx, build := r.StartTask(context.Background(), "build")
+	_, _ = fmt.Fprint(Capture(ctx, nil), "\x1b[32mok\x1b[0m\n")
+	build.Finish(nil)
+
+	pctx, suite := r.StartSuite(ctx, "ci (parallel, line 4)")
+	_, lint := r.StartTask(pctx, "lint")
+	lint.Finish(errors.New("task \"lint\": command \"golangci-lint run\" failed: exit status 1\ndetails"))
+	_, docs := r.StartTask(pctx, "docs")
+	docs.Skip("empty value is falsy")
+	docs.Finish(nil)
+	suite.Finish()
+
+	var buf bytes.Buffer
+	if err := r.WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit() error: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if doc.Tests != 3 || doc.Failures != 1 || doc.Skipped != 1 {
+		t.Errorf("totals = %d/%d/%d, want 3/1/1", doc.Tests, doc.Failures, doc.Skipped)
+	}
+	if len(doc.Suites) != 2 {
+		t.Fatalf("expected 2 suites, got %d", len(doc.Suites))
+	}
+	if doc.Suites[0].Name != "bab" || doc.Suites[1].Name != "ci (parallel, line 4)" {
+		t.Errorf("unexpected suite names: %q, %q", doc.Suites[0].Name, doc.Suites[1].Name)
+	}
+
+	buildCase := doc.Suites[0].Cases[0]
+	if buildCase.SystemOut != "ok\n" {
+		t.Errorf("system-out = %q, want ANSI-stripped output", buildCase.SystemOut)
+	}
+
+	lintCase := doc.Suites[1].Cases[0]
+	if lintCase.Failure == nil {
+		t.Fatal("expected failure for lint")
+	}
+	if strings.Contains(lintCase.Failure.Message, "\n") {
+		t.Errorf("failure message should be a single line, got %q", lintCase.Failure.Message)
+	}
+	if lintCase.ClassName != "ci (parallel, line 4)" {
+		t.Errorf("classname = %q, want suite name", lintCase.ClassName)
+	}
+	if doc.Suites[1].Cases[1].Skipped == nil {
+		t.Error("expected docs to be skipped")
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/changes"
+)
+
+func (r *Runner) isAffected(name string, tasks babfile.TaskMap) (bool, error) {
+	return r.affectedTask(name, tasks, make(map[string]bool))
+}
+
+func (r *Runner) affectedTask(name string, tasks babfile.TaskMap, seen map[string]bool) (bool, error) {
+	task, ok := tasks[name]
+	if !ok || seen[name] {
+		return false, nil
+	}
+	seen[name] = true
+
+	dir, err := r.resolveDir(task, "", r.varContext(r.GlobalVars, task.Name, task.Line))
+	if err != nil {
+		dir = task.Root()
+	}
+	set, err := changes.For(dir, r.Base)
+	if err != nil {
+		return false, fmt.Errorf("task %q: %w", name, err)
+	}
+	if len(task.Sources) > 0 && set.Match(dir, task.Sources) || len(task.Sources) == 0 && set.Under(dir) {
+		return true, nil
+	}
+
+	for _, ref := range taskRefs(task.Deps, task.Run) {
+		affected, err := r.affectedTask(ref, tasks, seen)
+		if err != nil || affected {
+			return affected, err
+		}
+	}
+	return false, nil
+}
+
+func taskRefs(deps []babfile.Dep, items []babfile.RunItem) []string {
+	refs := babfile.DepNames(deps)
+	for _, item := range items {
+		switch v := item.(type) {
+		case babfile.TaskRun:
+			refs = append(refs, v.Task)
+		case babfile.ParallelRun:
+			refs = append(refs, taskRefs(nil, v.Items)...)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/interpolate"
+)
+
+type taskArgs struct {
+	vars map[string]any
+	env  map[string]string
+}
+
+func (a taskArgs) key(name string) string {
+	if len(a.vars) == 0 && len(a.env) == 0 {
+		return name
+	}
+	parts := make([]string, 0, len(a.vars)+len(a.env))
+	for _, k := range slices.Sorted(maps.Keys(a.vars)) {
+		parts = append(parts, k+"="+argValue(a.vars[k]))
+	}
+	for _, k := range slices.Sorted(maps.Keys(a.env)) {
+		parts = append(parts, "env."+k+"="+strconv.Quote(a.env[k]))
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+func argValue(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return strconv.Quote(interpolate.Render(v))
+}
+
+func (r *Runner) resolveArgs(vars babfile.VarMap, env map[string]string, ctx *interpolate.Context) (taskArgs, error) {
+	var args taskArgs
+	if len(vars) > 0 {
+		args.vars = make(map[string]any, len(vars))
+		for k, v := range vars {
+			resolved, err := interpolate.ResolveValue(v, ctx)
+			if err != nil {
+				return args, err
+			}
+			args.vars[k] = resolved
+		}
+	}
+	env, err := r.interpolateEnv(env, ctx)
+	if err != nil {
+		return args, err
+	}
+	args.env = env
+	return args, nil
+}
+
+func (r *Runner) resolveTaskVars(task *babfile.Task, args taskArgs) (map[string]any, error) {
+	own, parent := task.Vars, r.GlobalVars
+	if len(args.vars) > 0 {
+		own = maps.Clone(task.Vars)
+		for k := range args.vars {
+			delete(own, k)
+		}
+		parent = babfile.MergeVarMaps(r.GlobalVars, args.vars)
+	}
+	return interpolate.ResolveVarsInContext(own, parent, r.varContext(nil, task.Name, task.Line))
+}
+
+func (r *Runner) depArgs(task *babfile.Task, dep babfile.Dep, taskVars map[string]any) (taskArgs, error) {
+	return r.resolveArgs(dep.Vars, dep.Env, r.varContext(taskVars, task.Name, cmp.Or(dep.Line, task.DepsLine, task.Line)))
+}
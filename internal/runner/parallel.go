@@ -1,7 +1,9 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +13,7 @@ import (
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/errs"
 	"github.com/bab-sh/bab/internal/interpolate"
 	"github.com/bab-sh/bab/internal/output"
 	"github.com/bab-sh/bab/internal/tui"
@@ -31,6 +34,23 @@ func pathToKey(path []int) string {
 	return strings.Join(parts, ".")
 }
 
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.WriteTo(w)
+}
+
 type syncState struct {
 	mu    sync.Mutex
 	state map[string]status
@@ -68,7 +88,7 @@ func (s *syncState) snapshot() map[string]status {
 	return cp
 }
 
-func (r *Runner) executeParallel(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, taskVars map[string]string, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, parentNoColor bool, pctx *ParallelContext) error {
+func (r *Runner) executeParallel(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, parentNoColor bool, pctx *ParallelContext) error {
 	labels := make([]string, len(pr.Items))
 	maxLabelLen := 0
 	for i := range pr.Items {
@@ -87,7 +107,7 @@ func (r *Runner) executeParallel(ctx context.Context, pr babfile.ParallelRun, ta
 		overrideOutput = pr.Output
 	}
 
-	if err := r.preResolveDeps(ctx, pr.Items, tasks, state, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
+	if err := r.preResolveDeps(ctx, pr.Items, task, tasks, state, taskVars, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
 		return err
 	}
 
@@ -98,22 +118,28 @@ func (r *Runner) executeParallel(ctx context.Context, pr babfile.ParallelRun, ta
 		return nil
 	}
 
+	ctx, suite := r.Report.StartSuite(ctx, fmt.Sprintf("%s (parallel, line %d)", task.Name, pr.Line))
+	defer suite.Finish()
+
 	isTerminal := term.IsTerminal(int(os.Stderr.Fd()))
 	hasParentTUI := pctx != nil && pctx.Program != nil
 	isTUIMode := pr.Mode == babfile.ParallelGrouped || pr.Mode == babfile.ParallelTabs
-	useTUI := isTUIMode && isTerminal && (stdout == nil || hasParentTUI)
+	useTUI := isTUIMode && isTerminal && !r.CI.Enabled() && (stdout == nil || hasParentTUI)
+	useBuffered := isTUIMode && r.CI.Enabled() && stdout == nil
 
-	if isTUIMode && !useTUI {
-		log.Debug("TUI parallel downgraded to interleaved", "mode", pr.Mode)
-	}
-
-	if useTUI {
+	switch {
+	case useTUI:
 		return r.executeParallelTUI(ctx, pr, task, tasks, state, labels, taskVars, taskEnv, overrideSilent, overrideOutput, noColor, pctx)
+	case useBuffered:
+		log.Debug("TUI parallel downgraded to buffered", "mode", pr.Mode, "ci", r.CI)
+		return r.executeParallelBuffered(ctx, pr, task, tasks, state, labels, taskVars, taskEnv, overrideSilent, overrideOutput, noColor)
+	case isTUIMode:
+		log.Debug("TUI parallel downgraded to interleaved", "mode", pr.Mode)
 	}
 	return r.executeParallelInterleaved(ctx, pr, task, tasks, state, labels, maxLabelLen, taskVars, taskEnv, overrideSilent, overrideOutput, noColor, stdout, stderr)
 }
 
-func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, labels []string, maxLabelLen int, taskVars map[string]string, taskEnv map[string]string, overrideSilent, overrideOutput *bool, noColor bool, parentOut, parentErr io.Writer) error {
+func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, labels []string, maxLabelLen int, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, noColor bool, parentOut, parentErr io.Writer) error {
 	var sem chan struct{}
 	if pr.Limit > 0 {
 		sem = make(chan struct{}, pr.Limit)
@@ -122,6 +148,8 @@ func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.Para
 	outDest, errDest := io.Writer(os.Stdout), io.Writer(os.Stderr)
 	if parentOut != nil {
 		outDest = parentOut
+	} else {
+		r.groups.Resume()
 	}
 	if parentErr != nil {
 		errDest = parentErr
@@ -131,6 +159,7 @@ func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.Para
 	var mu sync.Mutex
 	var firstErr error
 	var firstErrOnce sync.Once
+	itemErrs := make([]error, len(pr.Items))
 
 	for i, item := range pr.Items {
 		wg.Add(1)
@@ -153,6 +182,7 @@ func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.Para
 			err := r.executeRunItem(ctx, runItem, task, tasks, state, taskVars, taskEnv, overrideSilent, overrideOutput, pw, pwErr, noColor, nil)
 			_ = pw.Flush()
 			_ = pwErr.Flush()
+			itemErrs[idx] = err
 			if err != nil {
 				firstErrOnce.Do(func() {
 					firstErr = fmt.Errorf("parallel item %q failed: %w", labels[idx], err)
@@ -162,10 +192,85 @@ func (r *Runner) executeParallelInterleaved(ctx context.Context, pr babfile.Para
 	}
 
 	wg.Wait()
-	return firstErr
+	return r.parallelErr(labels, itemErrs, firstErr)
 }
 
-func (r *Runner) executeParallelTUI(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, labels []string, taskVars map[string]string, taskEnv map[string]string, overrideSilent, overrideOutput *bool, noColor bool, pctx *ParallelContext) error {
+func (r *Runner) executeParallelBuffered(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, labels []string, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, noColor bool) error {
+	var sem chan struct{}
+	if pr.Limit > 0 {
+		sem = make(chan struct{}, pr.Limit)
+	}
+
+	var wg sync.WaitGroup
+	var firstErr error
+	var firstErrOnce sync.Once
+	itemErrs := make([]error, len(pr.Items))
+
+	for i, item := range pr.Items {
+		wg.Add(1)
+
+		go func(idx int, runItem babfile.RunItem) {
+			defer wg.Done()
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					itemErrs[idx] = context.Canceled
+					return
+				}
+				defer func() { <-sem }()
+			}
+
+			buf := &syncBuffer{}
+			err := r.executeRunItem(ctx, runItem, task, tasks, state, taskVars, taskEnv, overrideSilent, overrideOutput, buf, buf, noColor, nil)
+			itemErrs[idx] = err
+
+			r.groups.Section(labels[idx], buf)
+
+			if err != nil {
+				firstErrOnce.Do(func() {
+					firstErr = fmt.Errorf("parallel item %q failed: %w", labels[idx], err)
+				})
+			}
+		}(i, item)
+	}
+
+	wg.Wait()
+	r.groups.Resume()
+
+	if !isSilent(overrideSilent, task.Silent, r.GlobalSilent) {
+		output.ParallelDone(labels, itemErrs)
+	}
+
+	return r.parallelErr(labels, itemErrs, firstErr)
+}
+
+func (r *Runner) parallelErr(labels []string, itemErrs []error, firstErr error) error {
+	if r.ExitCode != ExitCodeMax {
+		return firstErr
+	}
+	worst := worstErr(itemErrs)
+	if worst < 0 {
+		return firstErr
+	}
+	return fmt.Errorf("parallel item %q failed: %w", labels[worst], itemErrs[worst])
+}
+
+func worstErr(itemErrs []error) int {
+	worst := -1
+	for i, err := range itemErrs {
+		if err == nil || errors.Is(err, context.Canceled) {
+			continue
+		}
+		if worst < 0 || errs.ExitCode(err) > errs.ExitCode(itemErrs[worst]) {
+			worst = i
+		}
+	}
+	return worst
+}
+
+func (r *Runner) executeParallelTUI(ctx context.Context, pr babfile.ParallelRun, task *babfile.Task, tasks babfile.TaskMap, state *syncState, labels []string, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, noColor bool, pctx *ParallelContext) error {
 	var program *tea.Program
 	var ownsProgram bool
 	var basePath []int
@@ -199,9 +304,9 @@ func (r *Runner) executeParallelTUI(ctx context.Context, pr babfile.ParallelRun,
 
 		var model tea.Model
 		if pr.Mode == babfile.ParallelTabs {
-			model = tui.NewTabsModel(tuiItems, workCancel)
+			model = tui.NewTabsModel(tuiItems, workCancel, ForceKill)
 		} else {
-			model = tui.NewGroupedModel(tuiItems, workCancel)
+			model = tui.NewGroupedModel(tuiItems, workCancel, ForceKill)
 		}
 
 		var err error
@@ -291,10 +396,10 @@ func (r *Runner) executeParallelTUI(ctx context.Context, pr babfile.ParallelRun,
 		}
 	}
 
-	return firstErr
+	return r.parallelErr(labels, itemErrs, firstErr)
 }
 
-func (r *Runner) executeRunItem(ctx context.Context, item babfile.RunItem, task *babfile.Task, tasks babfile.TaskMap, state *syncState, taskVars map[string]string, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
+func (r *Runner) executeRunItem(ctx context.Context, item babfile.RunItem, task *babfile.Task, tasks babfile.TaskMap, state *syncState, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
 	switch v := item.(type) {
 	case babfile.CommandRun:
 		shell, shellArg := shellCommand()
@@ -305,12 +410,16 @@ func (r *Runner) executeRunItem(ctx context.Context, item babfile.RunItem, task
 			log.Info("Would run task", "task", v.Task)
 			return nil
 		}
+		args, err := r.resolveArgs(v.Vars, v.Env, r.varContext(taskVars, task.Name, v.Line))
+		if err != nil {
+			return err
+		}
 		effSilent := firstNonNil(v.Silent, overrideSilent)
 		effOutput := firstNonNil(v.Output, overrideOutput)
-		return r.runTask(ctx, v.Task, tasks, state, false, effSilent, effOutput, stdout, stderr, noColor, pctx)
+		return r.runTask(ctx, v.Task, args, tasks, state, false, effSilent, effOutput, stdout, stderr, noColor, pctx)
 
 	case babfile.LogRun:
-		logCtx := interpolate.NewContextWithLocation(taskVars, r.BabfilePath, v.Line)
+		logCtx := r.varContext(taskVars, task.Name, v.Line)
 		interpolatedLog, err := interpolate.Interpolate(v.Log, logCtx)
 		if err != nil {
 			return err
@@ -330,7 +439,7 @@ func (r *Runner) executeRunItem(ctx context.Context, item babfile.RunItem, task
 	}
 }
 
-func (r *Runner) preResolveDeps(ctx context.Context, items []babfile.RunItem, tasks babfile.TaskMap, state *syncState, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
+func (r *Runner) preResolveDeps(ctx context.Context, items []babfile.RunItem, parent *babfile.Task, tasks babfile.TaskMap, state *syncState, parentVars map[string]any, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
 	seen := make(map[string]bool)
 	for _, item := range items {
 		tr, ok := item.(babfile.TaskRun)
@@ -341,16 +450,32 @@ func (r *Runner) preResolveDeps(ctx context.Context, items []babfile.RunItem, ta
 		if !exists {
 			return fmt.Errorf("parallel item references unknown task %q", tr.Task)
 		}
+		if len(task.Deps) == 0 {
+			continue
+		}
+		args, err := r.resolveArgs(tr.Vars, tr.Env, r.varContext(parentVars, parent.Name, tr.Line))
+		if err != nil {
+			return err
+		}
+		taskVars, err := r.resolveTaskVars(task, args)
+		if err != nil {
+			return err
+		}
 		for _, dep := range task.Deps {
-			if seen[dep] {
+			depArgs, err := r.depArgs(task, dep, taskVars)
+			if err != nil {
+				return err
+			}
+			key := depArgs.key(dep.Task)
+			if seen[key] {
 				continue
 			}
-			seen[dep] = true
-			if state.get(dep) == done {
+			seen[key] = true
+			if state.get(key) == done {
 				continue
 			}
-			if err := r.runTask(ctx, dep, tasks, state, false, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
-				return fmt.Errorf("parallel pre-dependency %q failed: %w", dep, err)
+			if err := r.runTask(ctx, dep.Task, depArgs, tasks, state, false, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
+				return fmt.Errorf("parallel pre-dependency %q failed: %w", key, err)
 			}
 		}
 	}
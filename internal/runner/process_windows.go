@@ -7,11 +7,17 @@ import (
 	"syscall"
 )
 
+var gracefulSignals []syscall.Signal
+
 func sysProcAttr() *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{}
 }
 
-func signalProcessGroup(cmd *exec.Cmd) error {
+func signalProcessGroup(cmd *exec.Cmd, _ syscall.Signal) error {
+	return killProcessGroup(cmd)
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
 	if cmd.Process == nil {
 		return nil
 	}
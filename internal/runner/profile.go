@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/dotenv"
+	"github.com/bab-sh/bab/internal/parser"
+	"github.com/bab-sh/bab/internal/tui"
+	"github.com/charmbracelet/log"
+)
+
+func (r *Runner) applyProfile(result *parser.ParseResult) (babfile.VarMap, map[string]string, error) {
+	name := r.Profile
+	if name == "" {
+		return result.GlobalVars, result.GlobalEnv, nil
+	}
+
+	profile, ok := result.Profiles[name]
+	if !ok {
+		if len(result.Profiles) == 0 {
+			return nil, nil, fmt.Errorf("unknown profile %q: %s defines no profiles", name, filepath.Base(result.Path))
+		}
+		available := slices.Sorted(maps.Keys(result.Profiles))
+		return nil, nil, fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(available, ", "))
+	}
+	r.profile = &profile
+	log.Debug("Using profile", "profile", name)
+
+	envMaps := []map[string]string{result.GlobalEnv}
+	for _, file := range profile.Dotenv {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(result.Path), file)
+		}
+		values, err := dotenv.Read(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		envMaps = append(envMaps, values)
+	}
+	envMaps = append(envMaps, profile.Env)
+
+	return babfile.MergeVarMaps(result.GlobalVars, profile.Vars), babfile.MergeEnvMaps(envMaps...), nil
+}
+
+func (r *Runner) confirmProfile(ctx context.Context, taskName string) error {
+	if r.profile == nil || !r.profile.Confirm || r.Yes || r.DryRun {
+		return nil
+	}
+	name := r.Profile
+	if r.CI.Enabled() || !tui.IsInteractive() {
+		return fmt.Errorf("profile %q requires confirmation, pass --yes to run without asking", name)
+	}
+	ok, err := tui.Confirm(ctx, fmt.Sprintf("Run %s with profile %s?", taskName, name))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: profile %q was not confirmed", tui.ErrPromptCancelled, name)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/condition"
+	"github.com/bab-sh/bab/internal/interpolate"
+	"github.com/bab-sh/bab/internal/tui"
+	"github.com/charmbracelet/log"
+)
+
+type pendingPrompt struct {
+	key    string
+	prompt babfile.PromptRun
+	dir    string
+}
+
+func promptKey(key string, p babfile.PromptRun) string {
+	return key + "@" + strconv.Itoa(p.Line)
+}
+
+func (r *Runner) collectPrompts(ctx context.Context, taskName string, tasks babfile.TaskMap) error {
+	r.collected = nil
+	if r.DryRun || !tui.IsInteractive() {
+		return nil
+	}
+
+	if r.Affected {
+		if affected, err := r.isAffected(taskName, tasks); err != nil || !affected {
+			return nil
+		}
+	}
+
+	var pending []pendingPrompt
+	r.scanTask(taskName, taskArgs{}, tasks, make(map[string]bool), &pending)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	formPrompts := make([]tui.FormPrompt, len(pending))
+	for i, p := range pending {
+		formPrompts[i] = tui.FormPrompt{Prompt: p.prompt, Dir: p.dir}
+	}
+
+	log.Debug("Collecting prompts up front", "count", len(pending))
+	results, err := tui.RunPromptForm(ctx, formPrompts)
+	if err != nil {
+		return fmt.Errorf("collecting prompts: %w", err)
+	}
+
+	r.collected = make(map[string]string, len(pending))
+	for i, p := range pending {
+		r.collected[p.key] = results[i]
+	}
+	return nil
+}
+
+func (r *Runner) scanTask(name string, args taskArgs, tasks babfile.TaskMap, seen map[string]bool, pending *[]pendingPrompt) {
+	key := args.key(name)
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+
+	task, ok := tasks[name]
+	if !ok || !task.ShouldRunOnPlatform(r.targetPlatform()) {
+		return
+	}
+
+	if task.When != "" {
+		whenCtx := r.varContext(babfile.MergeVarMaps(r.GlobalVars, args.vars), task.Name, task.Line)
+		if result, err := condition.Evaluate(task.When, whenCtx); err != nil || !result.ShouldRun {
+			return
+		}
+	}
+
+	taskVars, err := r.resolveTaskVars(task, args)
+	if err != nil {
+		return
+	}
+
+	for _, dep := range task.Deps {
+		if depArgs, err := r.depArgs(task, dep, taskVars); err == nil {
+			r.scanTask(dep.Task, depArgs, tasks, seen, pending)
+		}
+	}
+
+	r.scanRunItems(task, key, task.Run, taskVars, make(map[string]bool), tasks, seen, pending)
+}
+
+func (r *Runner) scanRunItems(task *babfile.Task, key string, items []babfile.RunItem, taskVars map[string]any, promptVars map[string]bool, tasks babfile.TaskMap, seen map[string]bool, pending *[]pendingPrompt) {
+	for _, item := range items {
+		r.scanRunItem(task, key, item, taskVars, promptVars, tasks, seen, pending)
+		if p, ok := item.(babfile.PromptRun); ok {
+			promptVars[p.Prompt] = true
+		}
+	}
+}
+
+func (r *Runner) scanRunItem(task *babfile.Task, key string, item babfile.RunItem, taskVars map[string]any, promptVars map[string]bool, tasks babfile.TaskMap, seen map[string]bool, pending *[]pendingPrompt) {
+	if !item.ShouldRunOnPlatform(r.targetPlatform()) {
+		return
+	}
+
+	if when := item.GetWhen(); when != "" {
+		if refersTo(promptVars, when) {
+			return
+		}
+		itemCtx := r.varContext(taskVars, task.Name, item.GetLine())
+		if result, err := condition.Evaluate(when, itemCtx); err != nil || !result.ShouldRun {
+			return
+		}
+	}
+
+	switch v := item.(type) {
+	case babfile.PromptRun:
+		if p, ok := r.collectablePrompt(task, key, v, taskVars, promptVars); ok {
+			*pending = append(*pending, p)
+		}
+	case babfile.TaskRun:
+		if args, err := r.resolveArgs(v.Vars, v.Env, r.varContext(taskVars, task.Name, v.Line)); err == nil {
+			r.scanTask(v.Task, args, tasks, seen, pending)
+		}
+	case babfile.ParallelRun:
+		r.scanRunItems(task, key, v.Items, taskVars, promptVars, tasks, seen, pending)
+	}
+}
+
+func (r *Runner) collectablePrompt(task *babfile.Task, key string, p babfile.PromptRun, taskVars map[string]any, promptVars map[string]bool) (pendingPrompt, bool) {
+	if p.Defer {
+		return pendingPrompt{}, false
+	}
+	if _, answered := r.Answers.Lookup(p.Prompt); answered {
+		return pendingPrompt{}, false
+	}
+
+	fields := append([]string{p.Message, p.Default, p.Placeholder, p.Glob}, p.Options...)
+	fields = append(fields, p.Defaults...)
+	if refersTo(promptVars, fields...) {
+		return pendingPrompt{}, false
+	}
+
+	promptCtx := r.varContext(taskVars, task.Name, p.Line)
+	interpolated, err := interpolatePrompt(p, promptCtx)
+	if err != nil {
+		return pendingPrompt{}, false
+	}
+	interpolated = r.withRemembered(interpolated)
+
+	var dir string
+	if interpolated.Type == babfile.PromptTypePath {
+		if dir, err = r.resolveDir(task, "", promptCtx); err != nil {
+			return pendingPrompt{}, false
+		}
+	}
+
+	return pendingPrompt{key: promptKey(key, p), prompt: interpolated, dir: dir}, true
+}
+
+func refersTo(vars map[string]bool, fields ...string) bool {
+	for _, field := range fields {
+		for _, ref := range interpolate.ExtractVarRefs(field) {
+			if vars[interpolate.RootName(ref)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Runner) withRemembered(p babfile.PromptRun) babfile.PromptRun {
+	if !p.Remember {
+		return p
+	}
+	value, ok := r.Memory.Get(r.BabfilePath, p.Prompt)
+	if !ok {
+		return p
+	}
+
+	switch p.Type {
+	case babfile.PromptTypeSelect:
+		if slices.Contains(p.Options, value) {
+			p.Default = value
+		}
+	case babfile.PromptTypeMultiselect:
+		var defaults []string
+		for _, v := range strings.Split(value, ",") {
+			if slices.Contains(p.Options, v) {
+				defaults = append(defaults, v)
+			}
+		}
+		p.Defaults = defaults
+	default:
+		p.Default = value
+	}
+	return p
+}
+
+func (r *Runner) rememberAnswer(p babfile.PromptRun, value string) {
+	if !p.Remember || r.DryRun {
+		return
+	}
+	if err := r.Memory.Set(r.BabfilePath, p.Prompt, value); err != nil {
+		log.Warn("Could not remember prompt answer", "prompt", p.Prompt, "error", err)
+	}
+}
+
+func promptValue(p babfile.PromptRun, value string) any {
+	if p.Type != babfile.PromptTypeMultiselect {
+		return value
+	}
+	list := []any{}
+	if value == "" {
+		return list
+	}
+	for _, item := range strings.Split(value, ",") {
+		list = append(list, item)
+	}
+	return list
+}
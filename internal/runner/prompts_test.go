@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bab-sh/bab/internal/answers"
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/remember"
+)
+
+func TestScanTaskCollectsPrompts(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"setup": &babfile.Task{
+			Name: "setup",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Line: 1, Prompt: "region", Type: babfile.PromptTypeInput, Message: "Region?"},
+			},
+		},
+		"notify": &babfile.Task{
+			Name: "notify",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Line: 2, Prompt: "channel", Type: babfile.PromptTypeInput, Message: "Channel?"},
+			},
+		},
+		"deploy": &babfile.Task{
+			Name: "deploy",
+			Deps: []babfile.Dep{{Task: "setup"}},
+			Vars: babfile.VarMap{"app": "api"},
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Line: 3, Prompt: "env", Type: babfile.PromptTypeSelect, Message: "Env for ${{ app }}?", Options: []string{"dev", "prod"}},
+				babfile.PromptRun{Line: 4, Prompt: "sure", Type: babfile.PromptTypeConfirm, Message: "Deploy to ${{ env }}?"},
+				babfile.PromptRun{Line: 5, Prompt: "reason", Type: babfile.PromptTypeInput, Message: "Why?", When: "${{ env }} == 'prod'"},
+				babfile.PromptRun{Line: 6, Prompt: "later", Type: babfile.PromptTypeInput, Message: "Later?", Defer: true},
+				babfile.PromptRun{Line: 7, Prompt: "token", Type: babfile.PromptTypePassword, Message: "Token?"},
+				babfile.PromptRun{Line: 8, Prompt: "never", Type: babfile.PromptTypeInput, Message: "Never?", When: "${{ app }} == 'web'"},
+				babfile.ParallelRun{Items: []babfile.RunItem{babfile.TaskRun{Task: "notify"}}},
+			},
+		},
+	}
+
+	set, err := answers.New([]string{"token=secret"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(false, "")
+	r.Answers = set
+
+	var pending []pendingPrompt
+	r.scanTask("deploy", taskArgs{}, tasks, make(map[string]bool), &pending)
+
+	var keys []string
+	for _, p := range pending {
+		keys = append(keys, p.key)
+	}
+	want := []string{"setup@1", "deploy@3", "notify@2"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("collected prompts = %v, want %v", keys, want)
+	}
+	if len(pending) > 1 && pending[1].prompt.Message != "Env for api?" {
+		t.Errorf("expected interpolated message, got %q", pending[1].prompt.Message)
+	}
+}
+
+func TestScanTaskKeysPromptsByArgs(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"deploy": &babfile.Task{
+			Name: "deploy",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Line: 1, Prompt: "sure", Type: babfile.PromptTypeConfirm, Message: "Deploy ${{ target }}?"},
+			},
+		},
+		"all": &babfile.Task{
+			Name: "all",
+			Deps: []babfile.Dep{{Task: "deploy", Vars: babfile.VarMap{"target": "eu"}}},
+			Run: []babfile.RunItem{
+				babfile.TaskRun{Task: "deploy", Vars: babfile.VarMap{"target": "us"}},
+				babfile.TaskRun{Task: "deploy", Vars: babfile.VarMap{"target": "eu"}},
+			},
+		},
+	}
+
+	var pending []pendingPrompt
+	New(false, "").scanTask("all", taskArgs{}, tasks, make(map[string]bool), &pending)
+
+	var keys, messages []string
+	for _, p := range pending {
+		keys = append(keys, p.key)
+		messages = append(messages, p.prompt.Message)
+	}
+	if want := []string{`deploy(target="eu")@1`, `deploy(target="us")@1`}; !slices.Equal(keys, want) {
+		t.Errorf("collected prompts = %v, want %v", keys, want)
+	}
+	if want := []string{"Deploy eu?", "Deploy us?"}; !slices.Equal(messages, want) {
+		t.Errorf("prompt messages = %v, want %v", messages, want)
+	}
+}
+
+func TestRememberedPromptAnswers(t *testing.T) {
+	store, err := remember.LoadFile(filepath.Join(t.TempDir(), "answers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	babfilePath := filepath.Join(t.TempDir(), "Babfile.yml")
+	if err := store.Set(babfilePath, "env", "prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := babfile.TaskMap{
+		"deploy": &babfile.Task{
+			Name: "deploy",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Prompt: "env", Type: babfile.PromptTypeSelect, Message: "Env?", Options: []string{"dev", "prod"}, Remember: true},
+				babfile.PromptRun{Prompt: "service", Type: babfile.PromptTypeInput, Message: "Service?", Remember: true},
+				babfile.CommandRun{Cmd: "test '${{ env }}:${{ service }}' = 'prod:api'"},
+			},
+		},
+	}
+
+	set, err := answers.New([]string{"service=api"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(false, "")
+	r.BabfilePath = babfilePath
+	r.Memory = store
+	r.Answers = set
+	if err := r.RunWithTasks(context.Background(), "deploy", tasks); err != nil {
+		t.Fatalf("RunWithTasks() error: %v", err)
+	}
+
+	if got, ok := store.Get(babfilePath, "service"); !ok || got != "api" {
+		t.Errorf("remembered service = %q, %v, want %q, true", got, ok, "api")
+	}
+}
@@ -0,0 +1,266 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/ci"
+	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/output"
+	"github.com/bab-sh/bab/internal/tui"
+	"github.com/charmbracelet/log"
+	"golang.org/x/term"
+)
+
+type project struct {
+	label  string
+	runner *Runner
+	task   string
+	tasks  babfile.TaskMap
+	err    error
+}
+
+func (r *Runner) RunRecursive(ctx context.Context, taskName string, paths []string, parallel bool) error {
+	projects, found := r.loadProjects(taskName, paths)
+	if !found {
+		return fmt.Errorf("%w: no Babfile defines %q", errs.ErrTaskNotFound, taskName)
+	}
+	if len(projects) == 0 {
+		return nil
+	}
+	for _, p := range projects {
+		if p.err == nil && p.runner.profile != nil && p.runner.profile.Confirm {
+			if err := p.runner.confirmProfile(ctx, taskName); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		if oldState, err := term.GetState(fd); err == nil {
+			defer func() { _ = term.Restore(fd, oldState) }()
+		}
+	}
+
+	for _, p := range projects {
+		if p.err != nil {
+			continue
+		}
+		if err := p.runner.collectPrompts(ctx, p.task, p.tasks); err != nil {
+			return err
+		}
+	}
+
+	labels := make([]string, len(projects))
+	for i, p := range projects {
+		labels[i] = p.label
+	}
+
+	isTerminal := term.IsTerminal(int(os.Stderr.Fd()))
+	var itemErrs []error
+	switch {
+	case !parallel:
+		itemErrs = r.runProjectsSequential(ctx, projects)
+	case isTerminal && !r.CI.Enabled() && !r.DryRun:
+		var err error
+		if itemErrs, err = r.runProjectsTUI(ctx, projects); err != nil {
+			return err
+		}
+	default:
+		itemErrs = r.runProjectsInterleaved(ctx, projects)
+	}
+
+	output.ProjectsDone(labels, itemErrs)
+	return r.projectsErr(labels, itemErrs)
+}
+
+func (r *Runner) loadProjects(taskName string, paths []string) ([]*project, bool) {
+	cwd, _ := os.Getwd()
+	var projects []*project
+	found := false
+	for _, path := range paths {
+		p := &project{label: projectLabel(cwd, path), runner: r.forProject(path)}
+
+		result, err := loadTasks(path, r.parseFunc())
+		if err == nil {
+			err = p.runner.load(result)
+		}
+		if err != nil {
+			p.err = err
+			found = true
+			projects = append(projects, p)
+			continue
+		}
+
+		p.task = p.runner.resolveTaskName(taskName)
+		task, ok := result.Tasks[p.task]
+		if !ok || !task.ShouldRunOnPlatform(r.targetPlatform()) {
+			log.Debug("Skipping project without task", "project", p.label, "task", taskName)
+			continue
+		}
+		found = true
+		if r.Affected {
+			affected, err := p.runner.isAffected(p.task, result.Tasks)
+			if err != nil {
+				p.err = err
+			} else if !affected {
+				log.Info("Skipping project, no changes affect it", "project", p.label, "task", taskName)
+				continue
+			}
+		}
+		p.tasks = result.Tasks
+		projects = append(projects, p)
+	}
+	return projects, found
+}
+
+func (r *Runner) forProject(path string) *Runner {
+	return &Runner{
+		DryRun:        r.DryRun,
+		Babfile:       path,
+		Report:        r.Report,
+		CI:            r.CI,
+		ExitCode:      r.ExitCode,
+		Answers:       r.Answers,
+		Memory:        r.Memory,
+		Platform:      r.Platform,
+		Version:       r.Version,
+		InvocationDir: r.InvocationDir,
+		Profile:       r.Profile,
+		Base:          r.Base,
+		Affected:      r.Affected,
+		Yes:           r.Yes,
+	}
+}
+
+func projectLabel(cwd, path string) string {
+	dir := filepath.Dir(path)
+	if rel, err := filepath.Rel(cwd, dir); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+		return filepath.ToSlash(rel)
+	}
+	return dir
+}
+
+func (p *project) run(ctx context.Context, stdout, stderr io.Writer, pctx *ParallelContext) error {
+	if p.err != nil {
+		_, _ = fmt.Fprintln(stderr, p.err)
+		return p.err
+	}
+	ctx, suite := p.runner.Report.StartSuite(ctx, p.label)
+	defer suite.Finish()
+	return p.runner.runMain(ctx, p.task, p.tasks, stdout, stderr, false, pctx)
+}
+
+func (r *Runner) runProjectsSequential(ctx context.Context, projects []*project) []error {
+	maxLabelLen := projectLabelWidth(projects)
+	groups := ci.NewGroups(r.CI, os.Stdout)
+	itemErrs := make([]error, len(projects))
+	var mu sync.Mutex
+
+	for i, p := range projects {
+		if ctx.Err() != nil {
+			itemErrs[i] = context.Canceled
+			continue
+		}
+		groups.Push(p.label)
+		pw := NewPrefixWriter(p.label, maxLabelLen, colorForPath([]int{i}), os.Stdout, &mu, false)
+		pwErr := NewPrefixWriter(p.label, maxLabelLen, colorForPath([]int{i}), os.Stderr, &mu, false)
+		itemErrs[i] = p.run(ctx, pw, pwErr, nil)
+		_ = pw.Flush()
+		_ = pwErr.Flush()
+		groups.Pop()
+	}
+	return itemErrs
+}
+
+func (r *Runner) runProjectsInterleaved(ctx context.Context, projects []*project) []error {
+	maxLabelLen := projectLabelWidth(projects)
+	itemErrs := make([]error, len(projects))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i, p := range projects {
+		wg.Add(1)
+		go func(idx int, p *project) {
+			defer wg.Done()
+			pw := NewPrefixWriter(p.label, maxLabelLen, colorForPath([]int{idx}), os.Stdout, &mu, false)
+			pwErr := NewPrefixWriter(p.label, maxLabelLen, colorForPath([]int{idx}), os.Stderr, &mu, false)
+			itemErrs[idx] = p.run(ctx, pw, pwErr, nil)
+			_ = pw.Flush()
+			_ = pwErr.Flush()
+		}(i, p)
+	}
+
+	wg.Wait()
+	return itemErrs
+}
+
+func (r *Runner) runProjectsTUI(ctx context.Context, projects []*project) ([]error, error) {
+	tuiItems := make([]tui.ParallelItem, len(projects))
+	for i, p := range projects {
+		tuiItems[i] = tui.ParallelItem{Label: p.label, Color: colorForPath([]int{i})}
+	}
+
+	workCtx, workCancel := context.WithCancel(ctx)
+	defer workCancel()
+
+	program, err := tui.RunParallel(tui.NewGroupedModel(tuiItems, workCancel, ForceKill))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start parallel TUI: %w", err)
+	}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	itemErrs := make([]error, len(projects))
+	var wg sync.WaitGroup
+
+	for i, p := range projects {
+		wg.Add(1)
+		go func(idx int, p *project) {
+			defer wg.Done()
+			key := pathToKey([]int{idx})
+			program.Send(tui.ItemStartMsg{Key: key})
+
+			lw := NewKeyLineWriter(key, program, false)
+			err := p.run(workCtx, lw, lw, &ParallelContext{Program: program, Path: []int{idx}})
+			lw.Flush()
+			itemErrs[idx] = err
+
+			program.Send(tui.ItemDoneMsg{Key: key, Err: err})
+		}(i, p)
+	}
+
+	wg.Wait()
+	program.Send(tui.AllDoneMsg{})
+	program.Wait()
+	return itemErrs, nil
+}
+
+func projectLabelWidth(projects []*project) int {
+	width := 0
+	for _, p := range projects {
+		width = max(width, len(p.label))
+	}
+	return width
+}
+
+func (r *Runner) projectsErr(labels []string, itemErrs []error) error {
+	idx := slices.IndexFunc(itemErrs, func(err error) bool { return err != nil })
+	if r.ExitCode == ExitCodeMax {
+		if worst := worstErr(itemErrs); worst >= 0 {
+			idx = worst
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	return fmt.Errorf("project %q failed: %w", labels[idx], itemErrs[idx])
+}
@@ -4,19 +4,27 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bab-sh/bab/internal/answers"
 	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/ci"
 	"github.com/bab-sh/bab/internal/condition"
 	"github.com/bab-sh/bab/internal/errs"
 	"github.com/bab-sh/bab/internal/finder"
 	"github.com/bab-sh/bab/internal/interpolate"
 	"github.com/bab-sh/bab/internal/output"
 	"github.com/bab-sh/bab/internal/parser"
+	"github.com/bab-sh/bab/internal/remember"
+	"github.com/bab-sh/bab/internal/remote"
+	"github.com/bab-sh/bab/internal/report"
 	"github.com/bab-sh/bab/internal/tui"
 	"github.com/charmbracelet/log"
 	"golang.org/x/term"
@@ -30,58 +38,197 @@ const (
 	done
 )
 
+type ExitCodeMode string
+
+const (
+	ExitCodeFirst ExitCodeMode = "first"
+	ExitCodeMax   ExitCodeMode = "max"
+)
+
+var ValidExitCodeModes = []ExitCodeMode{ExitCodeFirst, ExitCodeMax}
+
+func (m ExitCodeMode) Valid() bool {
+	for _, v := range ValidExitCodeModes {
+		if m == v {
+			return true
+		}
+	}
+	return false
+}
+
 type Runner struct {
-	DryRun       bool
-	Babfile      string
-	BabfilePath  string
-	GlobalVars   map[string]string
-	GlobalEnv    map[string]string
-	GlobalSilent *bool
-	GlobalOutput *bool
-	GlobalDir    string
-	Aliases      map[string]string
+	DryRun        bool
+	Babfile       string
+	BabfilePath   string
+	GlobalVars    map[string]any
+	GlobalEnv     map[string]string
+	GlobalSilent  *bool
+	GlobalOutput  *bool
+	GlobalDir     string
+	Aliases       map[string]string
+	Report        *report.Recorder
+	CI            ci.Provider
+	ExitCode      ExitCodeMode
+	Answers       *answers.Set
+	Memory        *remember.Store
+	Platform      string
+	Version       string
+	InvocationDir string
+	Profile       string
+	Base          string
+	Affected      bool
+	Global        bool
+	Yes           bool
+	profile       *babfile.Profile
+	unfetched     []string
+	groups        *ci.Groups
+	collected     map[string]string
 }
 
 func New(dryRun bool, babfile string) *Runner {
 	return &Runner{DryRun: dryRun, Babfile: babfile}
 }
 
+type parseFunc func(path string) (*parser.ParseResult, error)
+
 func LoadTasks(customPath string) (*parser.ParseResult, error) {
-	var path string
-	if customPath != "" {
-		path = customPath
-	} else {
-		p, err := finder.FindBabfile()
-		if err != nil {
-			return nil, err
+	return loadTasks(customPath, parser.Parse)
+}
+
+func LoadTasksWithGlobal(customPath string) (*parser.ParseResult, error) {
+	result, err := LoadTasks(customPath)
+	if err != nil {
+		return nil, err
+	}
+	includeGlobal(result)
+	return result, nil
+}
+
+func LoadGlobalTasks() (*parser.ParseResult, error) {
+	return loadGlobalTasks(parser.Parse)
+}
+
+func loadTasks(customPath string, parse parseFunc) (*parser.ParseResult, error) {
+	path, err := babfilePath(customPath)
+	if err != nil {
+		return nil, err
+	}
+	return parse(path)
+}
+
+func loadGlobalTasks(parse parseFunc) (*parser.ParseResult, error) {
+	path, err := finder.FindGlobalBabfile()
+	if err != nil {
+		return nil, err
+	}
+	result, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for _, task := range result.Tasks {
+		if task.BaseDir == "" || task.BaseDir == filepath.Dir(result.Path) {
+			task.BaseDir = cwd
 		}
-		path = p
 	}
-	return parser.Parse(path)
+	return result, nil
+}
+
+func includeGlobal(result *parser.ParseResult) {
+	path, err := finder.FindGlobalBabfile()
+	if err != nil || sameFile(path, result.Path) {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	if err := parser.IncludeGlobal(result, path, cwd); err != nil {
+		log.Warn("Skipping global Babfile", "path", path, "error", err)
+	}
+}
+
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+func UpdateIncludes(customPath string) (*remote.Lock, error) {
+	path, err := babfilePath(customPath)
+	if err != nil {
+		return nil, err
+	}
+	return parser.UpdateIncludes(path)
+}
+
+func (r *Runner) parseFunc() parseFunc {
+	if r.DryRun {
+		return parser.Parse
+	}
+	return parser.ParseForRun
+}
+
+func babfilePath(customPath string) (string, error) {
+	if customPath != "" {
+		return customPath, nil
+	}
+	return finder.FindBabfile()
 }
 
 func (r *Runner) Run(ctx context.Context, taskName string) error {
-	result, err := LoadTasks(r.Babfile)
+	var result *parser.ParseResult
+	var err error
+	if r.Global {
+		result, err = loadGlobalTasks(r.parseFunc())
+	} else {
+		result, err = loadTasks(r.Babfile, r.parseFunc())
+	}
 	if err != nil {
 		return err
 	}
+	if err := r.load(result); err != nil {
+		return err
+	}
+
+	resolvedName := r.resolveTaskName(taskName)
+	if err := r.confirmProfile(ctx, resolvedName); err != nil {
+		return err
+	}
+
+	return r.RunWithTasks(ctx, resolvedName, result.Tasks)
+}
 
+func (r *Runner) load(result *parser.ParseResult) error {
 	r.BabfilePath = result.Path
 	r.Aliases = result.Aliases
+	r.unfetched = result.Unfetched
 
-	resolvedVars, err := interpolate.ResolveVarsWithLocation(result.GlobalVars, nil, r.BabfilePath, 0)
+	globalVars, globalEnv, err := r.applyProfile(result)
+	if err != nil {
+		return err
+	}
+
+	resolvedVars, err := interpolate.ResolveVarsInContext(globalVars, nil, r.varContext(nil, "", 0))
 	if err != nil {
 		return fmt.Errorf("resolving global variables: %w", err)
 	}
 	r.GlobalVars = resolvedVars
-	r.GlobalEnv = result.GlobalEnv
+	r.GlobalEnv = globalEnv
 	r.GlobalSilent = result.GlobalSilent
 	r.GlobalOutput = result.GlobalOutput
 	r.GlobalDir = result.GlobalDir
+	return nil
+}
 
-	resolvedName := r.resolveTaskName(taskName)
-
-	return r.RunWithTasks(ctx, resolvedName, result.Tasks)
+func (r *Runner) targetPlatform() string {
+	if r.Platform != "" {
+		return r.Platform
+	}
+	return babfile.CurrentPlatform()
 }
 
 func (r *Runner) resolveTaskName(name string) string {
@@ -101,8 +248,20 @@ func (r *Runner) RunWithTasks(ctx context.Context, taskName string, tasks babfil
 		}
 	}
 
+	if err := r.collectPrompts(ctx, taskName, tasks); err != nil {
+		return err
+	}
+
+	return r.runMain(ctx, taskName, tasks, nil, nil, false, nil)
+}
+
+func (r *Runner) runMain(ctx context.Context, taskName string, tasks babfile.TaskMap, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
+	r.groups = ci.NewGroups(r.CI, os.Stdout)
+	if r.DryRun && r.Profile != "" {
+		log.Info("Would use profile", "profile", r.Profile)
+	}
 	state := &syncState{state: make(map[string]status)}
-	return r.runTask(ctx, taskName, tasks, state, true, nil, nil, nil, nil, false, nil)
+	return r.runTask(ctx, taskName, taskArgs{}, tasks, state, true, nil, nil, stdout, stderr, noColor, pctx)
 }
 
 func isSilent(vals ...*bool) bool {
@@ -132,8 +291,9 @@ func firstNonNil(vals ...*bool) *bool {
 	return nil
 }
 
-func (r *Runner) runTask(ctx context.Context, name string, tasks babfile.TaskMap, state *syncState, isMain bool, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
-	switch state.claim(name) {
+func (r *Runner) runTask(ctx context.Context, name string, args taskArgs, tasks babfile.TaskMap, state *syncState, isMain bool, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) (err error) {
+	key := args.key(name)
+	switch state.claim(key) {
 	case done:
 		return nil
 	case running:
@@ -152,71 +312,117 @@ func (r *Runner) runTask(ctx context.Context, name string, tasks babfile.TaskMap
 		return &errs.TaskNotFoundError{
 			TaskName:  name,
 			Available: available,
+			Unfetched: r.unfetched,
 		}
 	}
 
+	ctx, tc := r.Report.StartTask(ctx, key)
+	defer func() { tc.Finish(err) }()
+
+	if platform := r.targetPlatform(); !task.ShouldRunOnPlatform(platform) {
+		if isMain {
+			return fmt.Errorf("task %q is not available on %s", name, platform)
+		}
+		log.Debug("Skipping task", "task", name, "reason", "platform", "platform", platform)
+		tc.Skip("not available on " + platform)
+		state.set(key, done)
+		return nil
+	}
+
 	if task.When != "" {
-		whenCtx := interpolate.NewContextWithLocation(r.GlobalVars, r.BabfilePath, task.Line)
+		whenCtx := r.varContext(babfile.MergeVarMaps(r.GlobalVars, args.vars), task.Name, task.Line)
 		result, err := condition.Evaluate(task.When, whenCtx)
 		if err != nil {
 			return fmt.Errorf("task %q: evaluating when condition: %w", name, err)
 		}
 		if !result.ShouldRun {
 			log.Debug("Skipping task", "task", name, "reason", "when condition", "detail", result.Reason)
-			state.set(name, done)
+			tc.Skip(result.Reason)
+			state.set(key, done)
+			return nil
+		}
+	}
+
+	if r.Affected && isMain {
+		affected, err := r.isAffected(name, tasks)
+		if err != nil {
+			return err
+		}
+		if !affected {
+			log.Info("Skipping task, no changes affect it", "task", name)
+			tc.Skip("not affected by changes")
+			state.set(key, done)
 			return nil
 		}
 	}
 
+	if stdout == nil {
+		r.groups.Push(name)
+		defer r.groups.Pop()
+	}
+
 	if !r.DryRun && !isSilent(overrideSilent, task.Silent, r.GlobalSilent) {
 		if stderr != nil {
 			if isMain {
-				_, _ = fmt.Fprintln(stderr, output.RenderTask(name))
+				_, _ = fmt.Fprintln(stderr, output.RenderTask(key, r.Profile))
 			} else {
-				_, _ = fmt.Fprintln(stderr, output.RenderDep(name))
+				_, _ = fmt.Fprintln(stderr, output.RenderDep(key))
 			}
 		} else {
 			if isMain {
-				output.Task(name)
+				output.Task(key, r.Profile)
 			} else {
-				output.Dep(name)
+				output.Dep(key)
 			}
 		}
 	}
 
-	for _, dep := range task.Deps {
-		log.Debug("Running dependency", "task", name, "dep", dep)
-		if err := r.runTask(ctx, dep, tasks, state, false, nil, nil, stdout, stderr, noColor, pctx); err != nil {
-			return fmt.Errorf("dependency %q failed: %w", dep, err)
+	if len(task.Deps) > 0 {
+		taskVars, err := r.resolveTaskVars(task, args)
+		if err != nil {
+			return err
+		}
+		for _, dep := range task.Deps {
+			depArgs, err := r.depArgs(task, dep, taskVars)
+			if err != nil {
+				return err
+			}
+			log.Debug("Running dependency", "task", key, "dep", depArgs.key(dep.Task))
+			if err := r.runTask(ctx, dep.Task, depArgs, tasks, state, false, nil, nil, stdout, stderr, noColor, pctx); err != nil {
+				return fmt.Errorf("dependency %q failed: %w", depArgs.key(dep.Task), err)
+			}
 		}
 	}
 
 	if len(task.Run) > 0 {
-		if err := r.executeTask(ctx, task, tasks, state, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
+		if err := r.executeTask(ctx, task, args, tasks, state, overrideSilent, overrideOutput, stdout, stderr, noColor, pctx); err != nil {
 			return err
 		}
 	}
 
-	state.set(name, done)
+	state.set(key, done)
 	return nil
 }
 
-func (r *Runner) executeTask(ctx context.Context, task *babfile.Task, tasks babfile.TaskMap, state *syncState, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
-	platform := runtime.GOOS
+func (r *Runner) executeTask(ctx context.Context, task *babfile.Task, args taskArgs, tasks babfile.TaskMap, state *syncState, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool, pctx *ParallelContext) error {
+	platform := r.targetPlatform()
 	executed := 0
 	skippedByCondition := 0
 
-	taskVars, err := interpolate.ResolveVarsWithLocation(task.Vars, r.GlobalVars, r.BabfilePath, task.Line)
+	taskVars, err := r.resolveTaskVars(task, args)
 	if err != nil {
 		return err
 	}
 
-	taskCtx := interpolate.NewContextWithLocation(taskVars, r.BabfilePath, task.Line)
+	taskCtx := r.varContext(taskVars, task.Name, task.Line)
 
 	taskEnv, err := r.interpolateEnv(babfile.MergeEnvMaps(r.GlobalEnv, task.Env), taskCtx)
 	if err != nil {
 		return err
 	}
+	if len(args.env) > 0 {
+		taskEnv = babfile.MergeEnvMaps(taskEnv, args.env)
+	}
 
 	log.Debug("Executing task", "name", task.Name, "runItems", len(task.Run), "dryRun", r.DryRun, "envVars", len(taskEnv), "vars", len(taskVars))
 
@@ -241,21 +447,45 @@ func (r *Runner) executeTask(ctx context.Context, task *babfile.Task, tasks babf
 
 		switch v := item.(type) {
 		case babfile.PromptRun:
-			promptCtx := interpolate.NewContextWithLocation(taskVars, r.BabfilePath, v.Line)
+			promptCtx := r.varContext(taskVars, task.Name, v.Line)
 
 			interpolated, err := interpolatePrompt(v, promptCtx)
 			if err != nil {
 				return err
 			}
+			interpolated = r.withRemembered(interpolated)
 
-			if r.DryRun {
+			answer, answered := r.Answers.Lookup(v.Prompt)
+			collected, isCollected := r.collected[promptKey(args.key(task.Name), v)]
+			switch {
+			case answered:
+				result, err := tui.ValidateAnswer(interpolated, answer)
+				if err != nil {
+					return fmt.Errorf("task %q: prompt %q: invalid answer %q: %w", task.Name, v.Prompt, answer, err)
+				}
+				taskVars[v.Prompt] = promptValue(v, result)
+				r.rememberAnswer(v, result)
+				log.Debug("Prompt answered", "var", v.Prompt)
+			case isCollected:
+				taskVars[v.Prompt] = promptValue(v, collected)
+				r.rememberAnswer(v, collected)
+				log.Debug("Prompt result stored", "var", v.Prompt, "value", collected)
+			case r.DryRun:
 				log.Info("Would prompt", "var", interpolated.Prompt, "type", interpolated.Type, "message", interpolated.Message)
-			} else {
-				result, err := tui.RunPrompt(ctx, interpolated, interpolated.Message)
+			default:
+				var promptDir string
+				if interpolated.Type == babfile.PromptTypePath {
+					promptDir, err = r.resolveDir(task, "", promptCtx)
+					if err != nil {
+						return fmt.Errorf("task %q: prompt %q: resolving dir: %w", task.Name, v.Prompt, err)
+					}
+				}
+				result, err := tui.RunPrompt(ctx, interpolated, interpolated.Message, promptDir)
 				if err != nil {
 					return fmt.Errorf("task %q: prompt %q: %w", task.Name, v.Prompt, err)
 				}
-				taskVars[v.Prompt] = result
+				taskVars[v.Prompt] = promptValue(v, result)
+				r.rememberAnswer(v, result)
 				log.Debug("Prompt result stored", "var", v.Prompt, "value", result)
 			}
 
@@ -263,7 +493,7 @@ func (r *Runner) executeTask(ctx context.Context, task *babfile.Task, tasks babf
 			if r.DryRun {
 				log.Info("Would run parallel", "items", len(v.Items), "mode", v.Mode)
 			} else {
-				parallelVars := make(map[string]string, len(taskVars))
+				parallelVars := make(map[string]any, len(taskVars))
 				for k, val := range taskVars {
 					parallelVars[k] = val
 				}
@@ -292,12 +522,49 @@ func (r *Runner) executeTask(ctx context.Context, task *babfile.Task, tasks babf
 	return nil
 }
 
-func (r *Runner) executeCommand(ctx context.Context, v babfile.CommandRun, task *babfile.Task, shell, shellArg string, taskVars map[string]string, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool) error {
+func (r *Runner) executeCommand(ctx context.Context, v babfile.CommandRun, task *babfile.Task, shell, shellArg string, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool) error {
+	if v.For == nil {
+		return r.executeCommandOnce(ctx, v, task, shell, shellArg, taskVars, taskEnv, overrideSilent, overrideOutput, stdout, stderr, noColor)
+	}
+
+	items, err := r.loopItems(v, task, taskVars)
+	if err != nil {
+		return fmt.Errorf("task %q: resolving for: %w", task.Name, err)
+	}
+	for i, item := range items {
+		loopVars := maps.Clone(taskVars)
+		loopVars["item"] = item
+		loopVars["index"] = strconv.Itoa(i)
+		if err := r.executeCommandOnce(ctx, v, task, shell, shellArg, loopVars, taskEnv, overrideSilent, overrideOutput, stdout, stderr, noColor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) loopItems(v babfile.CommandRun, task *babfile.Task, taskVars map[string]any) ([]any, error) {
+	loopCtx := r.varContext(taskVars, task.Name, v.Line)
+	var (
+		resolved any
+		err      error
+	)
+	if ref, ok := v.For.(string); ok {
+		resolved, err = interpolate.Resolve(ref, loopCtx)
+	} else {
+		resolved, err = interpolate.ResolveValue(v.For, loopCtx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return interpolate.Items(resolved), nil
+}
+
+func (r *Runner) executeCommandOnce(ctx context.Context, v babfile.CommandRun, task *babfile.Task, shell, shellArg string, taskVars map[string]any, taskEnv map[string]string, overrideSilent, overrideOutput *bool, stdout, stderr io.Writer, noColor bool) error {
 	if strings.TrimSpace(v.Cmd) == "" {
 		return fmt.Errorf("task %q has an empty command", task.Name)
 	}
 
-	cmdCtx := interpolate.NewContextWithLocation(taskVars, r.BabfilePath, v.Line)
+	cmdCtx := r.varContext(taskVars, task.Name, v.Line)
 	interpolatedCmd, err := interpolate.Interpolate(v.Cmd, cmdCtx)
 	if err != nil {
 		return err
@@ -326,29 +593,54 @@ func (r *Runner) executeCommand(ctx context.Context, v babfile.CommandRun, task
 		}
 	}
 	showOutput := isOutput(v.Output, overrideOutput, task.Output, r.GlobalOutput)
-	if stdout != nil {
+	if stdout == nil {
+		r.groups.Resume()
+	}
+	cc := r.Report.StartCommand(ctx, interpolatedCmd)
+	capture := report.Capture(ctx, cc)
+	timeout := killTimeout(v, task)
+
+	switch {
+	case stdout != nil:
 		var outW, errW io.Writer
 		if showOutput {
 			outW, errW = stdout, stderr
 		}
-		if err := runCommandWithWriters(ctx, shell, shellArg, interpolatedCmd, cmdEnv, outW, errW, false, noColor, cmdDir); err != nil {
-			return fmt.Errorf("task %q: command %q failed: %w", task.Name, interpolatedCmd, err)
-		}
-	} else {
-		if err := runCommand(ctx, shell, shellArg, interpolatedCmd, cmdEnv, showOutput, cmdDir); err != nil {
-			return fmt.Errorf("task %q: command %q failed: %w", task.Name, interpolatedCmd, err)
+		err = runCommandWithWriters(ctx, shell, shellArg, interpolatedCmd, cmdEnv, teeWriter(outW, capture), teeWriter(errW, capture), false, noColor, cmdDir, timeout)
+	case capture != nil:
+		var outW, errW io.Writer
+		if showOutput {
+			outW, errW = os.Stdout, os.Stderr
 		}
+		err = runCommandWithWriters(ctx, shell, shellArg, interpolatedCmd, cmdEnv, teeWriter(outW, capture), teeWriter(errW, capture), showOutput, false, cmdDir, timeout)
+	default:
+		err = runCommand(ctx, shell, shellArg, interpolatedCmd, cmdEnv, showOutput, cmdDir, timeout)
+	}
+	if err != nil {
+		err = &errs.CommandError{Path: task.SourcePath, Line: v.Line, Task: task.Name, Cmd: interpolatedCmd, Err: err}
+	}
+	cc.Finish(err)
+	return err
+}
+
+func teeWriter(w, capture io.Writer) io.Writer {
+	switch {
+	case capture == nil:
+		return w
+	case w == nil:
+		return capture
+	default:
+		return io.MultiWriter(w, capture)
 	}
-	return nil
 }
 
-func (r *Runner) shouldSkipRunItem(item babfile.RunItem, taskVars map[string]string, taskName string, index int) (bool, error) {
+func (r *Runner) shouldSkipRunItem(item babfile.RunItem, taskVars map[string]any, taskName string, index int) (bool, error) {
 	whenCond := item.GetWhen()
 	if whenCond == "" {
 		return false, nil
 	}
 
-	itemCtx := interpolate.NewContextWithLocation(taskVars, r.BabfilePath, item.GetLine())
+	itemCtx := r.varContext(taskVars, taskName, item.GetLine())
 	result, err := condition.Evaluate(whenCond, itemCtx)
 	if err != nil {
 		return false, fmt.Errorf("task %q: run[%d]: evaluating when condition: %w", taskName, index, err)
@@ -362,6 +654,17 @@ func (r *Runner) shouldSkipRunItem(item babfile.RunItem, taskVars map[string]str
 	return false, nil
 }
 
+func (r *Runner) varContext(vars map[string]any, taskName string, line int) *interpolate.Context {
+	ctx := interpolate.NewContextWithLocation(vars, r.BabfilePath, line)
+	ctx.Task = taskName
+	ctx.Version = r.Version
+	ctx.InvocationDir = r.InvocationDir
+	ctx.Profile = r.Profile
+	ctx.Base = r.Base
+	ctx.Platform = r.targetPlatform()
+	return ctx
+}
+
 func interpolatePrompt(p babfile.PromptRun, ctx *interpolate.Context) (babfile.PromptRun, error) {
 	msg, err := interpolate.Interpolate(p.Message, ctx)
 	if err != nil {
@@ -383,6 +686,13 @@ func interpolatePrompt(p babfile.PromptRun, ctx *interpolate.Context) (babfile.P
 		}
 	}
 
+	if p.Glob != "" {
+		p.Glob, err = interpolate.Interpolate(p.Glob, ctx)
+		if err != nil {
+			return p, err
+		}
+	}
+
 	if len(p.Options) > 0 {
 		options := make([]string, len(p.Options))
 		for i, opt := range p.Options {
@@ -425,7 +735,7 @@ func (r *Runner) interpolateEnv(env map[string]string, ctx *interpolate.Context)
 }
 
 func (r *Runner) resolveDir(task *babfile.Task, cmdDir string, ctx *interpolate.Context) (string, error) {
-	baseDir := filepath.Dir(task.SourcePath)
+	baseDir := task.Root()
 
 	dir := cmdDir
 	if dir == "" {
@@ -471,24 +781,26 @@ func shellCommand() (string, string) {
 	return "sh", "-c"
 }
 
-func runCommand(ctx context.Context, shell, shellArg, command string, env map[string]string, showOutput bool, dir string) error {
+func runCommand(ctx context.Context, shell, shellArg, command string, env map[string]string, showOutput bool, dir string, killTimeout time.Duration) error {
 	var stdout, stderr io.Writer
 	if showOutput {
 		stdout = os.Stdout
 		stderr = os.Stderr
 	}
-	return runCommandWithWriters(ctx, shell, shellArg, command, env, stdout, stderr, showOutput, false, dir)
+	return runCommandWithWriters(ctx, shell, shellArg, command, env, stdout, stderr, showOutput, false, dir, killTimeout)
 }
 
 func isRealTerminal(w io.Writer) bool {
 	return w == os.Stdout || w == os.Stderr
 }
 
-func runCommandWithWriters(ctx context.Context, shell, shellArg, command string, env map[string]string, stdout, stderr io.Writer, connectStdin bool, noColor bool, dir string) error {
+func runCommandWithWriters(ctx context.Context, shell, shellArg, command string, env map[string]string, stdout, stderr io.Writer, connectStdin bool, noColor bool, dir string, killTimeout time.Duration) error {
 	cmd := exec.CommandContext(ctx, shell, shellArg, command)
 	cmd.SysProcAttr = sysProcAttr()
+	exited := make(chan struct{})
 	cmd.Cancel = func() error {
-		return signalProcessGroup(cmd)
+		go stopProcess(cmd, killTimeout, exited)
+		return nil
 	}
 
 	piped := (stdout != nil && !isRealTerminal(stdout)) || (stderr != nil && !isRealTerminal(stderr))
@@ -527,6 +839,7 @@ func runCommandWithWriters(ctx context.Context, shell, shellArg, command string,
 	}
 
 	err := cmd.Run()
+	close(exited)
 	if err != nil && ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -565,9 +878,9 @@ func buildChainSlice(current string, tasks babfile.TaskMap, state map[string]sta
 		}
 
 		for _, dep := range task.Deps {
-			if state[dep] == running {
-				chain = append(chain, dep)
-				if dep == current {
+			if state[dep.Task] == running {
+				chain = append(chain, dep.Task)
+				if dep.Task == current {
 					return chain
 				}
 				break
@@ -2,12 +2,20 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bab-sh/bab/internal/answers"
 	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/bab-sh/bab/internal/errs"
 	"github.com/bab-sh/bab/internal/interpolate"
 )
 
@@ -35,7 +43,7 @@ func TestRunWithDependencies(t *testing.T) {
 		"test": &babfile.Task{
 			Name: "test",
 			Run:  []babfile.RunItem{babfile.CommandRun{Cmd: "echo testing"}},
-			Deps: []string{"build"},
+			Deps: []babfile.Dep{{Task: "build"}},
 		},
 	}
 
@@ -69,12 +77,12 @@ func TestRunCircularDependency(t *testing.T) {
 		"a": &babfile.Task{
 			Name: "a",
 			Run:  []babfile.RunItem{babfile.CommandRun{Cmd: "echo a"}},
-			Deps: []string{"b"},
+			Deps: []babfile.Dep{{Task: "b"}},
 		},
 		"b": &babfile.Task{
 			Name: "b",
 			Run:  []babfile.RunItem{babfile.CommandRun{Cmd: "echo b"}},
-			Deps: []string{"a"},
+			Deps: []babfile.Dep{{Task: "a"}},
 		},
 	}
 
@@ -818,7 +826,7 @@ func TestResolveDirInterpolation(t *testing.T) {
 		Dir:        "./${{ target }}",
 	}
 
-	ctx := &interpolate.Context{Vars: map[string]string{"target": "release"}}
+	ctx := &interpolate.Context{Vars: map[string]any{"target": "release"}}
 	dir, err := r.resolveDir(task, "", ctx)
 	if err != nil {
 		t.Fatalf("resolveDir() error: %v", err)
@@ -1003,7 +1011,7 @@ func TestRunIncludedTaskUsesSourceDir(t *testing.T) {
 
 func TestInterpolatePromptFields(t *testing.T) {
 	ctx := &interpolate.Context{
-		Vars: map[string]string{
+		Vars: map[string]any{
 			"name":    "Alice",
 			"env_val": "production",
 		},
@@ -1047,3 +1055,661 @@ func TestInterpolatePromptFields(t *testing.T) {
 		t.Error("original Options slice was mutated")
 	}
 }
+
+func TestRunCommandErrorLocation(t *testing.T) {
+	tmpDir := t.TempDir()
+	babfilePath := filepath.Join(tmpDir, "Babfile.yml")
+
+	tasks := babfile.TaskMap{
+		"fail": &babfile.Task{
+			Name:       "fail",
+			SourcePath: babfilePath,
+			Run:        []babfile.RunItem{babfile.CommandRun{Line: 7, Cmd: "exit 2"}},
+		},
+	}
+
+	r := New(false, "")
+	r.BabfilePath = babfilePath
+
+	err := r.RunWithTasks(context.Background(), "fail", tasks)
+	var cmdErr *errs.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected CommandError, got %T: %v", err, err)
+	}
+	if cmdErr.Path != babfilePath || cmdErr.Line != 7 {
+		t.Errorf("location = %s:%d, want %s:7", cmdErr.Path, cmdErr.Line, babfilePath)
+	}
+	if want := `task "fail": command "exit 2" failed: exit status 2`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestRunCommandExitCode(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"fail": &babfile.Task{
+			Name: "fail",
+			Run:  []babfile.RunItem{babfile.CommandRun{Cmd: "exit 3"}},
+		},
+	}
+
+	r := New(false, "")
+	err := r.RunWithTasks(context.Background(), "fail", tasks)
+	if code := errs.ExitCode(err); code != 3 {
+		t.Errorf("ExitCode() = %d, want 3 (err: %v)", code, err)
+	}
+}
+
+func TestRunBuiltinVars(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"build": &babfile.Task{
+			Name: "build",
+			Vars: babfile.VarMap{"label": "${{ bab.task }}-${{ bab.os }}"},
+			Run: []babfile.RunItem{
+				babfile.CommandRun{Cmd: "test '${{ label }}' = 'build-" + runtime.GOOS + "'"},
+				babfile.CommandRun{Cmd: "echo skipped; exit 1", When: "${{ bab.task }} != 'build'"},
+			},
+		},
+	}
+
+	r := New(false, "")
+	if err := r.RunWithTasks(context.Background(), "build", tasks); err != nil {
+		t.Fatalf("RunWithTasks() error: %v", err)
+	}
+}
+
+func TestRunForLoop(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	tasks := babfile.TaskMap{
+		"deploy": &babfile.Task{
+			Name: "deploy",
+			Vars: babfile.VarMap{
+				"services": []any{"api", "web"},
+				"ports":    map[string]any{"api": "8080"},
+			},
+			Run: []babfile.RunItem{
+				babfile.CommandRun{Cmd: "echo ${{ index }}:${{ item }} >> " + out, For: "${{ services }}"},
+				babfile.CommandRun{Cmd: "echo ${{ item.key }}=${{ item.value }} >> " + out, For: "${{ ports }}"},
+				babfile.CommandRun{Cmd: "echo ${{ item }} >> " + out, For: []any{"x-${{ services[0] }}"}},
+				babfile.CommandRun{Cmd: "exit 1", For: []any{"a"}, When: "false"},
+			},
+		},
+	}
+
+	r := New(false, "")
+	if err := r.RunWithTasks(context.Background(), "deploy", tasks); err != nil {
+		t.Fatalf("RunWithTasks() error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if want := "0:api\n1:web\napi=8080\nx-api\n"; string(data) != want {
+		t.Errorf("expected output %q, got %q", want, string(data))
+	}
+}
+
+func TestRunPlatformOverride(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"build": &babfile.Task{
+			Name: "build",
+			Deps: []babfile.Dep{{Task: "sign"}},
+			Run: []babfile.RunItem{
+				babfile.CommandRun{Cmd: "echo arm", Platforms: []babfile.Platform{"*/arm64"}},
+			},
+		},
+		"sign": &babfile.Task{
+			Name:      "sign",
+			Platforms: []babfile.Platform{"darwin"},
+			Run:       []babfile.RunItem{babfile.CommandRun{Cmd: "exit 1"}},
+		},
+	}
+
+	r := New(true, "")
+	r.Platform = "linux/arm64"
+	if err := r.RunWithTasks(context.Background(), "build", tasks); err != nil {
+		t.Fatalf("RunWithTasks() error: %v", err)
+	}
+
+	r = New(true, "")
+	r.Platform = "linux/amd64"
+	err := r.RunWithTasks(context.Background(), "build", tasks)
+	if err == nil || !strings.Contains(err.Error(), `no run items for platform "linux/amd64"`) {
+		t.Errorf("expected no run items error, got %v", err)
+	}
+
+	r = New(true, "")
+	r.Platform = "linux/arm64"
+	err = r.RunWithTasks(context.Background(), "sign", tasks)
+	if err == nil || !strings.Contains(err.Error(), `task "sign" is not available on linux/arm64`) {
+		t.Errorf("expected unavailable task error, got %v", err)
+	}
+}
+
+func TestRunTaskBaseDir(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := babfile.TaskMap{
+		"web:build": &babfile.Task{
+			Name:       "web:build",
+			SourcePath: filepath.Join(t.TempDir(), "Babfile.yml"),
+			BaseDir:    root,
+			Run: []babfile.RunItem{
+				babfile.CommandRun{Cmd: "test \"$(pwd -P)\" = '" + root + "'"},
+				babfile.CommandRun{Cmd: "test \"$(pwd -P)\" = '" + filepath.Join(root, "sub") + "'", Dir: "sub"},
+			},
+		},
+	}
+
+	r := New(false, "")
+	if err := r.RunWithTasks(context.Background(), "web:build", tasks); err != nil {
+		t.Fatalf("RunWithTasks() error: %v", err)
+	}
+}
+
+func TestRunParallelExitCodeMode(t *testing.T) {
+	tests := []struct {
+		mode ExitCodeMode
+		want int
+	}{
+		{ExitCodeFirst, 2},
+		{ExitCodeMax, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			tasks := babfile.TaskMap{
+				"par": &babfile.Task{
+					Name: "par",
+					Run: []babfile.RunItem{babfile.ParallelRun{
+						Mode:   babfile.ParallelInterleaved,
+						Labels: []string{"low", "high"},
+						Items: []babfile.RunItem{
+							babfile.CommandRun{Cmd: "exit 2"},
+							babfile.CommandRun{Cmd: "sleep 0.2; exit 5"},
+						},
+					}},
+				},
+			}
+
+			r := New(false, "")
+			r.ExitCode = tt.mode
+			err := r.RunWithTasks(context.Background(), "par", tasks)
+			if code := errs.ExitCode(err); code != tt.want {
+				t.Errorf("ExitCode() = %d, want %d (err: %v)", code, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunCancelEscalatesSignals(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "interrupted")
+
+	tasks := babfile.TaskMap{
+		"serve": &babfile.Task{
+			Name:        "serve",
+			KillTimeout: 100 * time.Millisecond,
+			Run: []babfile.RunItem{babfile.CommandRun{
+				Cmd: "trap 'touch " + marker + "' INT; trap '' TERM; while true; do sleep 0.05; done",
+			}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	r := New(false, "")
+	start := time.Now()
+	err := r.RunWithTasks(ctx, "serve", tasks)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command was not killed after kill_timeout, took %v", elapsed)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected SIGINT to be forwarded before killing: %v", err)
+	}
+}
+
+func TestRunKillTimeoutIsTotal(t *testing.T) {
+	tasks := babfile.TaskMap{
+		"serve": &babfile.Task{
+			Name:        "serve",
+			KillTimeout: 400 * time.Millisecond,
+			Run: []babfile.RunItem{babfile.CommandRun{
+				Cmd: "trap '' INT TERM; while true; do sleep 0.05; done",
+			}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	r := New(false, "")
+	start := time.Now()
+	if err := r.RunWithTasks(ctx, "serve", tasks); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start) - 200*time.Millisecond; elapsed < 350*time.Millisecond || elapsed > 750*time.Millisecond {
+		t.Errorf("expected SIGKILL after a total of kill_timeout, took %v", elapsed)
+	}
+}
+
+func TestRunPromptAnswers(t *testing.T) {
+	minTargets := 1
+	tasks := babfile.TaskMap{
+		"deploy": &babfile.Task{
+			Name: "deploy",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Prompt: "env", Type: babfile.PromptTypeSelect, Message: "Env?", Options: []string{"dev", "prod"}},
+				babfile.PromptRun{Prompt: "token", Type: babfile.PromptTypePassword, Message: "Token?"},
+				babfile.PromptRun{Prompt: "targets", Type: babfile.PromptTypeMultiselect, Message: "Targets?", Options: []string{"api", "web"}, Min: &minTargets},
+				babfile.CommandRun{Cmd: "test '${{ env }}:${{ token }}:${{ targets }}' = 'prod:secret:api,web'"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		answers []string
+		wantErr string
+	}{
+		{"valid", []string{"env=prod", "token=secret", "targets=api, web"}, ""},
+		{"not in options", []string{"env=qa", "token=secret", "targets=api"}, `invalid answer "qa": must be one of: dev, prod`},
+		{"below min", []string{"env=prod", "token=secret", "targets="}, "select at least 1 option(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := answers.New(tt.answers, "")
+			if err != nil {
+				t.Fatalf("answers.New() error: %v", err)
+			}
+
+			r := New(false, "")
+			r.Answers = set
+			err = r.RunWithTasks(context.Background(), "deploy", tasks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("RunWithTasks() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RunWithTasks() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunPromptTextPathDateAnswers(t *testing.T) {
+	today := time.Now().Format(babfile.PromptDateLayout)
+	tasks := babfile.TaskMap{
+		"backup": &babfile.Task{
+			Name: "backup",
+			Run: []babfile.RunItem{
+				babfile.PromptRun{Prompt: "notes", Type: babfile.PromptTypeText, Message: "Notes?"},
+				babfile.PromptRun{Prompt: "file", Type: babfile.PromptTypePath, Message: "File?", Glob: "*.sql"},
+				babfile.PromptRun{Prompt: "day", Type: babfile.PromptTypeDate, Message: "Day?"},
+				babfile.PromptRun{Prompt: "at", Type: babfile.PromptTypeTime, Message: "Time?"},
+				babfile.CommandRun{Cmd: "test '${{ file }} ${{ day }} ${{ at }}' = 'db/001.sql " + today + " 03:30'"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		answers []string
+		wantErr string
+	}{
+		{"valid", []string{"notes=fixes", "file=db/001.sql", "day=today", "at=03:30"}, ""},
+		{"path not matching glob", []string{"notes=fixes", "file=db/001.txt", "day=today", "at=03:30"}, "must match *.sql"},
+		{"invalid date", []string{"notes=fixes", "file=db/001.sql", "day=18/10", "at=03:30"}, "must match the format YYYY-MM-DD"},
+		{"invalid time", []string{"notes=fixes", "file=db/001.sql", "day=today", "at=3pm"}, "must match the format HH:MM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := answers.New(tt.answers, "")
+			if err != nil {
+				t.Fatalf("answers.New() error: %v", err)
+			}
+
+			r := New(false, "")
+			r.Answers = set
+			err = r.RunWithTasks(context.Background(), "backup", tasks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("RunWithTasks() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RunWithTasks() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunPromptDefaultsValidated(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  babfile.PromptRun
+		wantErr string
+	}{
+		{"path default not matching glob", babfile.PromptRun{Prompt: "file", Type: babfile.PromptTypePath, Message: "File?", Glob: "*.sql", Default: "db/001.txt"}, `invalid default "db/001.txt": must match *.sql`},
+		{"input default failing validate", babfile.PromptRun{Prompt: "version", Type: babfile.PromptTypeInput, Message: "Version?", Validate: `^v\d+$`, Default: "latest"}, `invalid default "latest"`},
+		{"valid path default", babfile.PromptRun{Prompt: "file", Type: babfile.PromptTypePath, Message: "File?", Glob: "*.sql", Default: "db/001.sql"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := babfile.TaskMap{
+				"backup": &babfile.Task{Name: "backup", Run: []babfile.RunItem{tt.prompt}},
+			}
+			err := New(false, "").RunWithTasks(context.Background(), "backup", tasks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("RunWithTasks() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RunWithTasks() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	root := t.TempDir()
+	babfiles := map[string]string{
+		"api/Babfile.yml":  "tasks:\n  test:\n    run:\n      - cmd: touch ran\n",
+		"web/Babfile.yml":  "tasks:\n  check:\n    alias: test\n    run:\n      - cmd: touch ran\n",
+		"docs/Babfile.yml": "tasks:\n  build:\n    run:\n      - cmd: touch ran\n",
+		"bad/Babfile.yml":  "tasks:\n  test:\n    run:\n      - cmd: exit 3\n",
+	}
+	var paths []string
+	for name, content := range babfiles {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%t", parallel), func(t *testing.T) {
+			for _, dir := range []string{"api", "web", "docs"} {
+				_ = os.Remove(filepath.Join(root, dir, "ran"))
+			}
+
+			r := New(false, "")
+			err := r.RunRecursive(context.Background(), "test", paths, parallel)
+			if err == nil || !strings.Contains(err.Error(), "bad") || errs.ExitCode(err) != 3 {
+				t.Errorf("expected failure of the bad project with exit code 3, got %v", err)
+			}
+
+			for dir, want := range map[string]bool{"api": true, "web": true, "docs": false} {
+				_, statErr := os.Stat(filepath.Join(root, dir, "ran"))
+				if got := statErr == nil; got != want {
+					t.Errorf("project %s ran = %t, want %t", dir, got, want)
+				}
+			}
+		})
+	}
+
+	err := New(false, "").RunRecursive(context.Background(), "deploy", paths, false)
+	if !errors.Is(err, errs.ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestRunAffected(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(time.Now().String()), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("api/main.go")
+	write("web/src/app.ts")
+	write("web/README.md")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=bab", "-c", "user.email=bab@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write("api/main.go")
+	write("web/README.md")
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	tasks := babfile.TaskMap{
+		"test": &babfile.Task{
+			Name:       "test",
+			SourcePath: filepath.Join(root, "Babfile.yml"),
+			Deps:       []babfile.Dep{{Task: "api:test"}, {Task: "web:test"}},
+			Run:        []babfile.RunItem{babfile.CommandRun{Cmd: "echo test >> " + marker}},
+		},
+		"api:test": &babfile.Task{
+			Name:       "api:test",
+			SourcePath: filepath.Join(root, "Babfile.yml"),
+			BaseDir:    filepath.Join(root, "api"),
+			Run:        []babfile.RunItem{babfile.CommandRun{Cmd: "echo api >> " + marker}},
+		},
+		"web:test": &babfile.Task{
+			Name:       "web:test",
+			SourcePath: filepath.Join(root, "Babfile.yml"),
+			BaseDir:    filepath.Join(root, "web"),
+			Sources:    []string{"src/**"},
+			Run:        []babfile.RunItem{babfile.CommandRun{Cmd: "echo web >> " + marker}},
+		},
+		"api:release": &babfile.Task{
+			Name:       "api:release",
+			SourcePath: filepath.Join(root, "Babfile.yml"),
+			BaseDir:    filepath.Join(root, "api"),
+			Run: []babfile.RunItem{
+				babfile.TaskRun{Task: "web:test"},
+				babfile.CommandRun{Cmd: "echo release >> " + marker},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		task string
+		want string
+	}{
+		{"test", "api\nweb\ntest\n"},
+		{"api:release", "web\nrelease\n"},
+		{"web:test", ""},
+	} {
+		t.Run(tt.task, func(t *testing.T) {
+			_ = os.Remove(marker)
+			r := New(false, "")
+			r.Base = "HEAD"
+			r.Affected = true
+			if err := r.RunWithTasks(context.Background(), tt.task, tasks); err != nil {
+				t.Fatalf("RunWithTasks() error: %v", err)
+			}
+			got, _ := os.ReadFile(marker)
+			if string(got) != tt.want {
+				t.Errorf("ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunProfiles(t *testing.T) {
+	root := t.TempDir()
+	babfileYAML := `vars:
+  stage: dev
+env:
+  REGION: local
+profiles:
+  staging:
+    vars:
+      stage: staging
+    dotenv: [.env.staging]
+  prod:
+    vars:
+      stage: prod
+    env:
+      REGION: eu-west-1
+    confirm: true
+tasks:
+  deploy:
+    run:
+      - cmd: echo "${{ stage }} $REGION $TOKEN ${{ bab.profile }}" > out
+      - cmd: touch prod-only
+        when: profile == 'prod'
+`
+	path := filepath.Join(root, "Babfile.yml")
+	if err := os.WriteFile(path, []byte(babfileYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".env.staging"), []byte("REGION=us-east-1\nTOKEN=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		profile  string
+		yes      bool
+		want     string
+		prodOnly bool
+		err      string
+	}{
+		{profile: "", want: "dev local"},
+		{profile: "staging", want: "staging us-east-1 abc staging"},
+		{profile: "prod", err: `profile "prod" requires confirmation`},
+		{profile: "prod", yes: true, want: "prod eu-west-1  prod", prodOnly: true},
+		{profile: "qa", err: `unknown profile "qa", available: prod, staging`},
+	} {
+		t.Run(tt.profile, func(t *testing.T) {
+			_ = os.Remove(filepath.Join(root, "out"))
+			_ = os.Remove(filepath.Join(root, "prod-only"))
+			r := New(false, path)
+			r.Profile = tt.profile
+			r.Yes = tt.yes
+			err := r.Run(context.Background(), "deploy")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			out, err := os.ReadFile(filepath.Join(root, "out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(root, "prod-only")); (err == nil) != tt.prodOnly {
+				t.Errorf("prod-only ran = %t, want %t", err == nil, tt.prodOnly)
+			}
+		})
+	}
+}
+
+func TestRunTaskArgs(t *testing.T) {
+	root := t.TempDir()
+	babfileYAML := `tasks:
+  build:
+    vars:
+      target: amd64
+      out: bin-${{ target }}
+    run:
+      - cmd: echo "${{ target }} $MODE" >> ${{ out }}
+  package:
+    deps:
+      - task: build
+        vars:
+          target: arm64
+    run:
+      - cmd: echo packaged
+  release:
+    vars:
+      arch: arm64
+    deps: [build, package]
+    run:
+      - task: build
+        vars:
+          target: ${{ arch }}
+      - task: build
+        vars:
+          target: riscv
+        env:
+          MODE: release
+`
+	path := filepath.Join(root, "Babfile.yml")
+	if err := os.WriteFile(path, []byte(babfileYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(false, path)
+	if err := r.Run(context.Background(), "release"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	for file, want := range map[string]string{
+		"bin-amd64": "amd64",
+		"bin-arm64": "arm64",
+		"bin-riscv": "riscv release",
+	} {
+		out, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestTaskArgsKey(t *testing.T) {
+	tests := []struct {
+		args taskArgs
+		want string
+	}{
+		{taskArgs{}, "build"},
+		{taskArgs{vars: map[string]any{"target": "arm64"}}, `build(target="arm64")`},
+		{taskArgs{vars: map[string]any{"os": "linux", "arch": "amd64"}, env: map[string]string{"CGO": "0"}}, `build(arch="amd64", os="linux", env.CGO="0")`},
+		{taskArgs{vars: map[string]any{"a": "1, b=2"}}, `build(a="1, b=2")`},
+		{taskArgs{vars: map[string]any{"a": "1", "b": "2"}}, `build(a="1", b="2")`},
+		{taskArgs{vars: map[string]any{"targets": []any{"api", "web"}}}, `build(targets=["api","web"])`},
+		{taskArgs{vars: map[string]any{"targets": "api,web"}}, `build(targets="api,web")`},
+	}
+	for _, tt := range tests {
+		if got := tt.args.key("build"); got != tt.want {
+			t.Errorf("key() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/bab-sh/bab/internal/babfile"
+	"github.com/charmbracelet/log"
+)
+
+var (
+	forceKill     = make(chan struct{})
+	forceKillOnce sync.Once
+)
+
+func ForceKill() {
+	forceKillOnce.Do(func() { close(forceKill) })
+}
+
+func killTimeout(v babfile.CommandRun, task *babfile.Task) time.Duration {
+	switch {
+	case v.KillTimeout > 0:
+		return v.KillTimeout
+	case task.KillTimeout > 0:
+		return task.KillTimeout
+	default:
+		return babfile.DefaultKillTimeout
+	}
+}
+
+func stopProcess(cmd *exec.Cmd, timeout time.Duration, done <-chan struct{}) {
+	step := timeout
+	if len(gracefulSignals) > 0 {
+		step = timeout / time.Duration(len(gracefulSignals))
+	}
+	for _, sig := range gracefulSignals {
+		if err := signalProcessGroup(cmd, sig); err != nil {
+			log.Debug("Failed to signal process group", "signal", sig, "error", err)
+		}
+
+		timer := time.NewTimer(step)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-forceKill:
+			timer.Stop()
+			_ = killProcessGroup(cmd)
+			return
+		case <-timer.C:
+		}
+	}
+
+	if err := killProcessGroup(cmd); err != nil {
+		log.Debug("Failed to kill process group", "error", err)
+	}
+}
@@ -62,6 +62,7 @@ type baseModel struct {
 	done      bool
 	cancelled bool
 	cancel    context.CancelFunc
+	force     func()
 }
 
 type itemState struct {
@@ -74,7 +75,7 @@ type itemState struct {
 	children []string
 }
 
-func newBaseModel(items []ParallelItem, cancel context.CancelFunc, maxLines int) baseModel {
+func newBaseModel(items []ParallelItem, cancel context.CancelFunc, force func(), maxLines int) baseModel {
 	stateMap := make(map[string]*itemState, len(items))
 	roots := make([]string, len(items))
 	for i, item := range items {
@@ -91,6 +92,7 @@ func newBaseModel(items []ParallelItem, cancel context.CancelFunc, maxLines int)
 		width:    80,
 		maxLines: maxLines,
 		cancel:   cancel,
+		force:    force,
 	}
 }
 
@@ -155,11 +157,15 @@ func (b *baseModel) handleMsg(msg tea.Msg) (handled bool, cmd tea.Cmd) {
 
 	case tea.KeyPressMsg:
 		if msg.String() == "ctrl+c" {
-			if !b.cancelled {
-				b.cancelled = true
-				if b.cancel != nil {
-					b.cancel()
+			if b.cancelled {
+				if b.force != nil {
+					b.force()
 				}
+				return true, nil
+			}
+			b.cancelled = true
+			if b.cancel != nil {
+				b.cancel()
 			}
 			return true, nil
 		}
@@ -191,6 +197,13 @@ func truncateLine(line string, maxWidth int) string {
 	return ansi.Truncate(line, maxWidth, "")
 }
 
+func stoppingText(item *itemState, cancelled bool) string {
+	if !cancelled || !item.started || item.done {
+		return ""
+	}
+	return " " + dimStyle.Render("stopping…")
+}
+
 func statusIcon(item *itemState, cancelled bool) string {
 	switch {
 	case item.done && item.err != nil && errors.Is(item.err, context.Canceled):
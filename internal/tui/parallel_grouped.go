@@ -15,8 +15,8 @@ type groupedModel struct {
 	baseModel
 }
 
-func NewGroupedModel(items []ParallelItem, cancel context.CancelFunc) tea.Model {
-	return groupedModel{baseModel: newBaseModel(items, cancel, groupedMaxLines)}
+func NewGroupedModel(items []ParallelItem, cancel context.CancelFunc, force func()) tea.Model {
+	return groupedModel{baseModel: newBaseModel(items, cancel, force, groupedMaxLines)}
 }
 
 func (m groupedModel) Init() tea.Cmd {
@@ -64,7 +64,7 @@ func (m groupedModel) renderItem(key string, indent string, depth int) []string
 
 	lines := make([]string, 0, groupedMaxLines+2)
 	lines = append(lines, truncateLine(
-		indent+dimStyle.Render("┌─")+" "+titleStyle.Render(item.label)+" "+status,
+		indent+dimStyle.Render("┌─")+" "+titleStyle.Render(item.label)+" "+status+stoppingText(item, m.cancelled),
 		m.width,
 	))
 
@@ -99,7 +99,7 @@ func (m groupedModel) renderCompactLine(item *itemState, indent string) string {
 	status := statusIcon(item, m.cancelled)
 	label := titleStyle.Render(item.label)
 
-	prefix := status + " " + label
+	prefix := status + " " + label + stoppingText(item, m.cancelled)
 	prefixWidth := ansi.StringWidth(prefix)
 
 	if item.done && item.err == nil {
@@ -17,9 +17,9 @@ type tabsModel struct {
 	viewport  viewport.Model
 }
 
-func NewTabsModel(items []ParallelItem, cancel context.CancelFunc) tea.Model {
+func NewTabsModel(items []ParallelItem, cancel context.CancelFunc, force func()) tea.Model {
 	return &tabsModel{
-		baseModel: newBaseModel(items, cancel, 0),
+		baseModel: newBaseModel(items, cancel, force, 0),
 		viewport:  viewport.New(),
 	}
 }
@@ -151,7 +151,7 @@ func (m *tabsModel) renderView() string {
 		}
 		style = style.Border(border)
 
-		label := statusIcon(item, m.cancelled) + " " + item.label
+		label := statusIcon(item, m.cancelled) + " " + item.label + stoppingText(item, m.cancelled)
 		renderedTabs = append(renderedTabs, style.Render(label))
 	}
 
@@ -205,7 +205,7 @@ func (m *tabsModel) buildContent(item *itemState, width int) string {
 			}
 			status := statusIcon(child, m.cancelled)
 			titleStyle := lipgloss.NewStyle().Foreground(child.color).Bold(true)
-			line := status + " " + titleStyle.Render(child.label)
+			line := status + " " + titleStyle.Render(child.label) + stoppingText(child, m.cancelled)
 			if child.done && child.err != nil {
 				line += "  " + failureStyle.Render(child.err.Error())
 			} else if len(child.lines) > 0 {
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"charm.land/huh/v2"
+	"github.com/bab-sh/bab/internal/answers"
 	"github.com/bab-sh/bab/internal/babfile"
 	"golang.org/x/term"
 )
@@ -24,27 +27,67 @@ const (
 	boolFalse = "false"
 )
 
-func RunPrompt(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
-	if !term.IsTerminal(int(os.Stdin.Fd())) {
+type FormPrompt struct {
+	Prompt babfile.PromptRun
+	Dir    string
+}
+
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func RunPrompt(ctx context.Context, p babfile.PromptRun, message, dir string) (string, error) {
+	if !IsInteractive() {
 		return handleNonInteractive(p)
 	}
 
-	switch p.Type {
-	case babfile.PromptTypeConfirm:
-		return runConfirm(ctx, p, message)
-	case babfile.PromptTypeInput:
-		return runInput(ctx, p, message)
-	case babfile.PromptTypeSelect:
-		return runSelect(ctx, p, message)
-	case babfile.PromptTypeMultiselect:
-		return runMultiselect(ctx, p, message)
-	case babfile.PromptTypePassword:
-		return runPassword(ctx, p, message)
-	case babfile.PromptTypeNumber:
-		return runNumber(ctx, p, message)
-	default:
-		return "", fmt.Errorf("unknown prompt type: %s", p.Type)
+	field, err := newPromptField(p, message, dir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := runForm(ctx, huh.NewForm(huh.NewGroup(field.fields...))); err != nil {
+		return "", err
+	}
+
+	return field.value(), nil
+}
+
+func Confirm(ctx context.Context, message string) (bool, error) {
+	if !IsInteractive() {
+		return false, ErrNoTTY
+	}
+	var result bool
+	confirm := huh.NewConfirm().
+		Title(message).
+		Value(&result)
+	if err := runForm(ctx, huh.NewForm(huh.NewGroup(confirm))); err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+func RunPromptForm(ctx context.Context, prompts []FormPrompt) ([]string, error) {
+	fields := make([]*promptField, len(prompts))
+	groups := make([]*huh.Group, len(prompts))
+	for i, fp := range prompts {
+		field, err := newPromptField(fp.Prompt, fp.Prompt.Message, fp.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %q: %w", fp.Prompt.Prompt, err)
+		}
+		fields[i] = field
+		groups[i] = huh.NewGroup(field.fields...)
+	}
+
+	if err := runForm(ctx, huh.NewForm(groups...)); err != nil {
+		return nil, err
+	}
+
+	results := make([]string, len(fields))
+	for i, field := range fields {
+		results[i] = field.value()
 	}
+	return results, nil
 }
 
 func handleNonInteractive(p babfile.PromptRun) (string, error) {
@@ -54,23 +97,179 @@ func handleNonInteractive(p babfile.PromptRun) (string, error) {
 			return normalizeConfirmDefault(p.Default), nil
 		}
 		return "", fmt.Errorf("%w: prompt %q requires 'default' for non-interactive mode", ErrNoTTY, p.Prompt)
-	case babfile.PromptTypeInput, babfile.PromptTypeSelect, babfile.PromptTypeNumber:
+	case babfile.PromptTypeInput, babfile.PromptTypeSelect, babfile.PromptTypeNumber, babfile.PromptTypeText, babfile.PromptTypePath,
+		babfile.PromptTypeDate, babfile.PromptTypeTime:
 		if p.Default != "" {
-			return p.Default, nil
+			return validateDefault(p, p.Default)
 		}
 		return "", fmt.Errorf("%w: prompt %q requires 'default' for non-interactive mode", ErrNoTTY, p.Prompt)
 	case babfile.PromptTypeMultiselect:
 		if len(p.Defaults) > 0 {
-			return strings.Join(p.Defaults, ","), nil
+			return validateDefault(p, strings.Join(p.Defaults, ","))
 		}
 		return "", fmt.Errorf("%w: prompt %q requires 'defaults' for non-interactive mode", ErrNoTTY, p.Prompt)
 	case babfile.PromptTypePassword:
-		return "", fmt.Errorf("%w: password prompts cannot run in non-interactive mode", ErrNoTTY)
+		return "", fmt.Errorf("%w: password prompt %q requires an answer via --answer or %s in non-interactive mode", ErrNoTTY, p.Prompt, answers.EnvName(p.Prompt))
 	default:
 		return "", fmt.Errorf("%w: prompt %q requires interactive input", ErrNoTTY, p.Prompt)
 	}
 }
 
+func validateDefault(p babfile.PromptRun, value string) (string, error) {
+	result, err := ValidateAnswer(p, value)
+	if err != nil {
+		return "", fmt.Errorf("prompt %q: invalid default %q: %w", p.Prompt, value, err)
+	}
+	return result, nil
+}
+
+func ValidateAnswer(p babfile.PromptRun, value string) (string, error) {
+	switch p.Type {
+	case babfile.PromptTypeConfirm:
+		switch strings.ToLower(value) {
+		case boolTrue, "yes", "y", "1":
+			return boolTrue, nil
+		case boolFalse, "no", "n", "0":
+			return boolFalse, nil
+		}
+		return "", errors.New("must be true or false")
+	case babfile.PromptTypeInput, babfile.PromptTypeText:
+		if p.Validate != "" {
+			return value, validateInput(p)(value)
+		}
+		return value, nil
+	case babfile.PromptTypePath:
+		return value, validatePath(p, ".")(value)
+	case babfile.PromptTypeDate, babfile.PromptTypeTime:
+		if value == "" {
+			value = p.Default
+		}
+		value = resolveDateTimeDefault(babfile.PromptRun{Type: p.Type, Default: value}, time.Now())
+		return value, validateDateTime(p)(value)
+	case babfile.PromptTypeSelect:
+		if !slices.Contains(p.Options, value) {
+			return "", fmt.Errorf("must be one of: %s", strings.Join(p.Options, ", "))
+		}
+		return value, nil
+	case babfile.PromptTypeMultiselect:
+		var selected []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			if !slices.Contains(p.Options, v) {
+				return "", fmt.Errorf("%q must be one of: %s", v, strings.Join(p.Options, ", "))
+			}
+			selected = append(selected, v)
+		}
+		return strings.Join(selected, ","), validateSelection(p)(selected)
+	case babfile.PromptTypePassword:
+		return value, nil
+	case babfile.PromptTypeNumber:
+		if value == "" {
+			value = p.Default
+		}
+		return value, validateNumber(p)(value)
+	default:
+		return "", fmt.Errorf("unknown prompt type: %s", p.Type)
+	}
+}
+
+func validateInput(p babfile.PromptRun) func(string) error {
+	re := regexp.MustCompile(p.Validate)
+	return func(s string) error {
+		if !re.MatchString(s) {
+			return fmt.Errorf("must match pattern: %s", p.Validate)
+		}
+		return nil
+	}
+}
+
+func validateSelection(p babfile.PromptRun) func([]string) error {
+	return func(selected []string) error {
+		if p.Min != nil && len(selected) < *p.Min {
+			return fmt.Errorf("select at least %d option(s)", *p.Min)
+		}
+		if p.Max != nil && len(selected) > *p.Max {
+			return fmt.Errorf("select at most %d option(s)", *p.Max)
+		}
+		return nil
+	}
+}
+
+func validateNumber(p babfile.PromptRun) func(string) error {
+	return func(s string) error {
+		if s == "" {
+			if p.Default != "" {
+				return nil
+			}
+			return errors.New("a number is required")
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		if p.Min != nil && n < *p.Min {
+			return fmt.Errorf("must be at least %d", *p.Min)
+		}
+		if p.Max != nil && n > *p.Max {
+			return fmt.Errorf("must be at most %d", *p.Max)
+		}
+		return nil
+	}
+}
+
+func validatePath(p babfile.PromptRun, root string) func(string) error {
+	return func(s string) error {
+		if s == "" {
+			return errors.New("a path is required")
+		}
+		if p.Glob == "" {
+			return nil
+		}
+		target := filepath.Base(s)
+		if strings.ContainsRune(p.Glob, '/') {
+			target = filepath.ToSlash(relativePath(root, s))
+		}
+		if ok, _ := filepath.Match(p.Glob, target); !ok {
+			return fmt.Errorf("must match %s", p.Glob)
+		}
+		return nil
+	}
+}
+
+func dateTimeLayout(t babfile.PromptType) (layout, keyword string) {
+	if t == babfile.PromptTypeTime {
+		return babfile.PromptTimeLayout, babfile.PromptTimeNow
+	}
+	return babfile.PromptDateLayout, babfile.PromptDateToday
+}
+
+func dateTimePlaceholder(t babfile.PromptType) string {
+	if t == babfile.PromptTypeTime {
+		return "HH:MM"
+	}
+	return "YYYY-MM-DD"
+}
+
+func validateDateTime(p babfile.PromptRun) func(string) error {
+	layout, _ := dateTimeLayout(p.Type)
+	return func(s string) error {
+		if _, err := time.Parse(layout, s); err != nil {
+			return fmt.Errorf("must match the format %s", dateTimePlaceholder(p.Type))
+		}
+		return nil
+	}
+}
+
+func resolveDateTimeDefault(p babfile.PromptRun, now time.Time) string {
+	layout, keyword := dateTimeLayout(p.Type)
+	if p.Default == keyword {
+		return now.Format(layout)
+	}
+	return p.Default
+}
+
 func normalizeConfirmDefault(s string) string {
 	lower := strings.ToLower(s)
 	switch lower {
@@ -81,7 +280,47 @@ func normalizeConfirmDefault(s string) string {
 	}
 }
 
-func runConfirm(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
+type promptField struct {
+	fields []huh.Field
+	value  func() string
+}
+
+func newPromptField(p babfile.PromptRun, message, dir string) (*promptField, error) {
+	switch p.Type {
+	case babfile.PromptTypeConfirm:
+		return confirmField(p, message), nil
+	case babfile.PromptTypeInput:
+		return inputField(p, message), nil
+	case babfile.PromptTypeSelect:
+		return selectField(p, message), nil
+	case babfile.PromptTypeMultiselect:
+		return multiselectField(p, message), nil
+	case babfile.PromptTypePassword:
+		return passwordField(p, message), nil
+	case babfile.PromptTypeNumber:
+		return numberField(p, message), nil
+	case babfile.PromptTypeText:
+		return textField(p, message), nil
+	case babfile.PromptTypePath:
+		return pathField(p, message, dir), nil
+	case babfile.PromptTypeDate, babfile.PromptTypeTime:
+		return dateTimeField(p, message), nil
+	default:
+		return nil, fmt.Errorf("unknown prompt type: %s", p.Type)
+	}
+}
+
+func runForm(ctx context.Context, form *huh.Form) error {
+	if err := form.RunWithContext(ctx); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return ErrPromptCancelled
+		}
+		return err
+	}
+	return nil
+}
+
+func confirmField(p babfile.PromptRun, message string) *promptField {
 	var result bool
 
 	if p.Default != "" {
@@ -92,22 +331,14 @@ func runConfirm(ctx context.Context, p babfile.PromptRun, message string) (strin
 		Title(message).
 		Value(&result)
 
-	form := huh.NewForm(huh.NewGroup(confirm))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
-		}
-		return "", err
+	return &promptField{
+		fields: []huh.Field{confirm},
+		value:  func() string { return strconv.FormatBool(result) },
 	}
-
-	return strconv.FormatBool(result), nil
 }
 
-func runInput(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
-	var result string
-	if p.Default != "" {
-		result = p.Default
-	}
+func inputField(p babfile.PromptRun, message string) *promptField {
+	result := p.Default
 
 	input := huh.NewInput().
 		Title(message).
@@ -118,31 +349,17 @@ func runInput(ctx context.Context, p babfile.PromptRun, message string) (string,
 	}
 
 	if p.Validate != "" {
-		re := regexp.MustCompile(p.Validate)
-		input.Validate(func(s string) error {
-			if !re.MatchString(s) {
-				return fmt.Errorf("must match pattern: %s", p.Validate)
-			}
-			return nil
-		})
+		input.Validate(validateInput(p))
 	}
 
-	form := huh.NewForm(huh.NewGroup(input))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
-		}
-		return "", err
+	return &promptField{
+		fields: []huh.Field{input},
+		value:  func() string { return result },
 	}
-
-	return result, nil
 }
 
-func runSelect(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
-	var result string
-	if p.Default != "" {
-		result = p.Default
-	}
+func selectField(p babfile.PromptRun, message string) *promptField {
+	result := p.Default
 
 	options := make([]huh.Option[string], len(p.Options))
 	for i, opt := range p.Options {
@@ -154,18 +371,13 @@ func runSelect(ctx context.Context, p babfile.PromptRun, message string) (string
 		Options(options...).
 		Value(&result)
 
-	form := huh.NewForm(huh.NewGroup(sel))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
-		}
-		return "", err
+	return &promptField{
+		fields: []huh.Field{sel},
+		value:  func() string { return result },
 	}
-
-	return result, nil
 }
 
-func runMultiselect(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
+func multiselectField(p babfile.PromptRun, message string) *promptField {
 	var result []string
 	if len(p.Defaults) > 0 {
 		result = make([]string, len(p.Defaults))
@@ -183,29 +395,16 @@ func runMultiselect(ctx context.Context, p babfile.PromptRun, message string) (s
 		Value(&result)
 
 	if p.Min != nil || p.Max != nil {
-		multi.Validate(func(selected []string) error {
-			if p.Min != nil && len(selected) < *p.Min {
-				return fmt.Errorf("select at least %d option(s)", *p.Min)
-			}
-			if p.Max != nil && len(selected) > *p.Max {
-				return fmt.Errorf("select at most %d option(s)", *p.Max)
-			}
-			return nil
-		})
+		multi.Validate(validateSelection(p))
 	}
 
-	form := huh.NewForm(huh.NewGroup(multi))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
-		}
-		return "", err
+	return &promptField{
+		fields: []huh.Field{multi},
+		value:  func() string { return strings.Join(result, ",") },
 	}
-
-	return strings.Join(result, ","), nil
 }
 
-func runPassword(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
+func passwordField(p babfile.PromptRun, message string) *promptField {
 	var result string
 
 	pw := huh.NewInput().
@@ -213,13 +412,7 @@ func runPassword(ctx context.Context, p babfile.PromptRun, message string) (stri
 		EchoMode(huh.EchoModePassword).
 		Value(&result)
 
-	form := huh.NewForm(huh.NewGroup(pw))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
-		}
-		return "", err
-	}
+	fields := []huh.Field{pw}
 
 	if p.Confirm != nil && *p.Confirm {
 		var confirmResult string
@@ -233,59 +426,123 @@ func runPassword(ctx context.Context, p babfile.PromptRun, message string) (stri
 				}
 				return nil
 			})
-
-		confirmForm := huh.NewForm(huh.NewGroup(confirmPw))
-		if err := confirmForm.RunWithContext(ctx); err != nil {
-			if errors.Is(err, huh.ErrUserAborted) {
-				return "", ErrPromptCancelled
-			}
-			return "", err
-		}
+		fields = append(fields, confirmPw)
 	}
 
-	return result, nil
+	return &promptField{
+		fields: fields,
+		value:  func() string { return result },
+	}
 }
 
-func runNumber(ctx context.Context, p babfile.PromptRun, message string) (string, error) {
-	var result string
-	if p.Default != "" {
-		result = p.Default
-	}
+func numberField(p babfile.PromptRun, message string) *promptField {
+	result := p.Default
 
 	input := huh.NewInput().
 		Title(message).
 		Value(&result).
-		Validate(func(s string) error {
-			if s == "" {
-				if p.Default != "" {
-					return nil
-				}
-				return errors.New("a number is required")
-			}
-			n, err := strconv.Atoi(s)
-			if err != nil {
-				return errors.New("must be a number")
-			}
-			if p.Min != nil && n < *p.Min {
-				return fmt.Errorf("must be at least %d", *p.Min)
-			}
-			if p.Max != nil && n > *p.Max {
-				return fmt.Errorf("must be at most %d", *p.Max)
-			}
-			return nil
-		})
+		Validate(validateNumber(p))
 
 	if p.Placeholder != "" {
 		input.Placeholder(p.Placeholder)
 	}
 
-	form := huh.NewForm(huh.NewGroup(input))
-	if err := form.RunWithContext(ctx); err != nil {
-		if errors.Is(err, huh.ErrUserAborted) {
-			return "", ErrPromptCancelled
+	return &promptField{
+		fields: []huh.Field{input},
+		value:  func() string { return result },
+	}
+}
+
+func textField(p babfile.PromptRun, message string) *promptField {
+	result := p.Default
+
+	text := huh.NewText().
+		Title(message).
+		ExternalEditor(true).
+		Value(&result)
+
+	if p.Placeholder != "" {
+		text.Placeholder(p.Placeholder)
+	}
+
+	if p.Validate != "" {
+		text.Validate(validateInput(p))
+	}
+
+	return &promptField{
+		fields: []huh.Field{text},
+		value:  func() string { return result },
+	}
+}
+
+func relativePath(root, path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(absRoot, path); err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	return path
+}
+
+func pathField(p babfile.PromptRun, message, dir string) *promptField {
+	root := dir
+	if root == "" {
+		root = "."
+	}
+
+	start := root
+	var result string
+	if p.Default != "" {
+		def := p.Default
+		if !filepath.IsAbs(def) {
+			def = filepath.Join(root, def)
+		}
+		if info, err := os.Stat(def); err == nil && info.IsDir() {
+			start = def
+		} else if _, err := os.Stat(filepath.Dir(def)); err == nil {
+			start = filepath.Dir(def)
+			result = def
 		}
-		return "", err
 	}
 
-	return result, nil
+	picker := huh.NewFilePicker().
+		Title(message).
+		CurrentDirectory(start).
+		Picking(true).
+		Validate(validatePath(p, root)).
+		Value(&result)
+
+	if suffix, ok := strings.CutPrefix(p.Glob, "*"); ok && suffix != "" && !strings.ContainsAny(suffix, "*?[/") {
+		picker.AllowedTypes([]string{suffix})
+	}
+
+	return &promptField{
+		fields: []huh.Field{picker},
+		value:  func() string { return relativePath(root, result) },
+	}
+}
+
+func dateTimeField(p babfile.PromptRun, message string) *promptField {
+	result := resolveDateTimeDefault(p, time.Now())
+
+	placeholder := p.Placeholder
+	if placeholder == "" {
+		placeholder = dateTimePlaceholder(p.Type)
+	}
+
+	input := huh.NewInput().
+		Title(message).
+		Placeholder(placeholder).
+		Validate(validateDateTime(p)).
+		Value(&result)
+
+	return &promptField{
+		fields: []huh.Field{input},
+		value:  func() string { return result },
+	}
 }
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bab-sh/bab/internal/babfile"
+)
+
+func TestValidatePath(t *testing.T) {
+	root, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		glob  string
+		input string
+		ok    bool
+	}{
+		{"relative match", "configs/*.yml", "configs/app.yml", true},
+		{"absolute match", "configs/*.yml", filepath.Join(root, "configs", "app.yml"), true},
+		{"absolute wrong extension", "configs/*.yml", filepath.Join(root, "configs", "app.json"), false},
+		{"absolute wrong directory", "configs/*.yml", filepath.Join(root, "other", "app.yml"), false},
+		{"absolute outside root", "configs/*.yml", filepath.Join(filepath.Dir(root), "configs", "app.yml"), false},
+		{"base name glob", "*.yml", filepath.Join(root, "deep", "app.yml"), true},
+		{"empty", "*.yml", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePath(babfile.PromptRun{Glob: tt.glob}, root)(tt.input)
+			if (err == nil) != tt.ok {
+				t.Errorf("validatePath(%q) error = %v, want ok %v", tt.input, err, tt.ok)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/bab-sh/bab/cmd"
 	"github.com/bab-sh/bab/internal/errs"
+	"github.com/bab-sh/bab/internal/runner"
 	"github.com/charmbracelet/log"
 )
 
@@ -33,24 +34,39 @@ func run() int {
 		Level:           log.InfoLevel,
 	}))
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
 
 	go func() {
-		<-ctx.Done()
-		stop()
+		sig := <-sigs
+		if s, ok := sig.(syscall.Signal); ok {
+			cancel(&errs.SignalError{Signal: s})
+		} else {
+			cancel(nil)
+		}
+		<-sigs
+		log.Warn("Force killing running commands")
+		runner.ForceKill()
+		signal.Stop(sigs)
 	}()
 
 	cmd.SetVersionInfo(version, commit, date)
 	if err := cmd.ExecuteContext(ctx); err != nil {
 		if isCancellation(err) {
-			return 0
+			if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+				return errs.ExitCode(cause)
+			}
+			return errs.ExitInterrupted
 		}
 		handleError(err)
-		return 1
+		return errs.ExitCode(err)
 	}
 
-	return 0
+	return errs.ExitOK
 }
 
 func handleError(err error) {